@@ -1,20 +1,130 @@
 package address
 
 import (
+	"crypto/rsa"
+	"errors"
 	"finalbruh/pkg/proto"
+	"finalbruh/pkg/utils"
+	"fmt"
 	"time"
 )
 
+// maxClockSkew is how far in the future an announcement's timestamp
+// may be before it is refused.
+const maxClockSkew = 10 * time.Minute
+
+// ErrUnsigned is returned by Verify for legacy records that carry no
+// signature.
+var ErrUnsigned = errors.New("address record is not signed")
+
 type Address struct {
-	Addr     string
-	LastSeen uint32
-	SentVer  time.Time
+	Addr string
+	// LastSeen is when the node at Addr was last seen, in Unix
+	// nanoseconds.
+	LastSeen int64
+
+	// PublicKey, Timestamp and Sig are set on records announced by the
+	// node at Addr, which signs them so they cannot be forged or kept
+	// looking fresh by anyone else.
+	PublicKey string
+	Timestamp int64
+	Sig       string
+}
+
+func New(addr string, lastSeen int64) *Address {
+	return &Address{Addr: addr, LastSeen: lastSeen}
+}
+
+// Copy returns a copy of a that can be changed without affecting a.
+func (a *Address) Copy() *Address {
+	c := *a
+	return &c
+}
+
+func announcementMsg(addr, pk string, ts int64) string {
+	return fmt.Sprintf("address:%v:%v:%d", addr, utils.Hash([]byte(pk)), ts)
 }
 
-func New(addr string, lastSeen uint32) *Address {
-	return &Address{Addr: addr, LastSeen: lastSeen, SentVer: time.Time{}}
+// Announce returns a record of addr signed by sk, the key of the node
+// reachable there.
+func Announce(sk *rsa.PrivateKey, addr string) (*Address, error) {
+	pk, err := utils.EncodePublicKey(&sk.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	a := New(addr, now.UnixNano())
+	a.PublicKey = pk
+	a.Timestamp = now.UnixNano()
+	a.Sig, err = utils.Sign(sk, announcementMsg(addr, pk, a.Timestamp))
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
 }
 
+func (a *Address) Signed() bool {
+	return a.Sig != ""
+}
+
+// Verify checks that the record was signed by the key it carries.
+func (a *Address) Verify() error {
+	if !a.Signed() {
+		return ErrUnsigned
+	}
+	pk, err := utils.DecodePublicKey(a.PublicKey)
+	if err != nil {
+		return err
+	}
+	if time.Unix(0, a.Timestamp).After(time.Now().Add(maxClockSkew)) {
+		return errors.New("address timestamp is in the future")
+	}
+	if !utils.Verify(pk, announcementMsg(a.Addr, a.PublicKey, a.Timestamp), a.Sig) {
+		return errors.New("invalid address signature")
+	}
+	return nil
+}
+
+// SignedBy reports whether the record carries the key pk.
+func (a *Address) SignedBy(pk *rsa.PublicKey) bool {
+	if !a.Signed() {
+		return false
+	}
+	theirs, err := utils.DecodePublicKey(a.PublicKey)
+	return err == nil && theirs.Equal(pk)
+}
+
+// Serialize encodes a, filling in the legacy seconds field for nodes
+// that predate 64-bit timestamps.
 func (a *Address) Serialize() *proto.Address {
-	return &proto.Address{Addr: a.Addr, LastSeen: a.LastSeen}
+	var legacy uint32
+	if a.LastSeen > 0 {
+		legacy = uint32(time.Unix(0, a.LastSeen).Unix())
+	}
+	return &proto.Address{
+		Addr:       a.Addr,
+		LastSeen:   legacy,
+		LastSeenNs: a.LastSeen,
+		SerPk:      a.PublicKey,
+		Timestamp:  a.Timestamp,
+		Sig:        a.Sig,
+	}
+}
+
+// Deserialize decodes a, reading the legacy seconds field when the
+// sender did not set the 64-bit one. A time too far in the future to
+// be honest is dropped, so it cannot make a record look fresh.
+func Deserialize(a *proto.Address) *Address {
+	lastSeen := a.LastSeenNs
+	if lastSeen == 0 && a.LastSeen != 0 {
+		lastSeen = time.Unix(int64(a.LastSeen), 0).UnixNano()
+	}
+	if time.Unix(0, lastSeen).After(time.Now().Add(maxClockSkew)) {
+		lastSeen = 0
+	}
+	addr := New(a.Addr, lastSeen)
+	addr.PublicKey = a.SerPk
+	addr.Timestamp = a.Timestamp
+	addr.Sig = a.Sig
+	return addr
 }
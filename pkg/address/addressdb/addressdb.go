@@ -3,16 +3,29 @@ package addressdb
 import (
 	"finalbruh/pkg/address"
 	"finalbruh/pkg/proto"
+	"time"
 )
 
 type AddressDb interface {
 	Add(*address.Address) error
+	Put(*address.Address) error
 	Get(string) *address.Address
-	UpdateLastSeen(string, uint32) error
+	UpdateLastSeen(string, int64) error
 	List() []*address.Address
 	Serialize() []*proto.Address
+	// Prune removes the addresses not heard of within maxAge, except
+	// those keep reports true for, and returns how many it removed.
+	Prune(maxAge time.Duration, keep func(string) bool) int
+	Close() error
+	// Discard releases the database without saving it.
+	Discard()
 }
 
-func New(eph bool, limit int) AddressDb {
-	return &EphemeralAddressDb{addresses: make(map[string]*address.Address), limit: limit}
+// New returns an address database of up to limit addresses. Unless it
+// is ephemeral it is kept in the file at path.
+func New(eph bool, limit int, path string) (AddressDb, error) {
+	if eph {
+		return NewAddrMan(limit), nil
+	}
+	return OpenAddrMan(path, limit)
 }
@@ -0,0 +1,543 @@
+package addressdb
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/netaddr"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/store"
+	mrand "math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	bucketSize = 16
+	// bucketsPerGroup bounds how many new buckets the addresses heard
+	// from one source group can land in, and how many tried buckets
+	// the addresses of one group can.
+	bucketsPerGroup = 4
+	// An address that has not announced itself for horizon, or failed
+	// maxFailures attempts without a success in a week, is terrible and
+	// gives way to any address colliding with it.
+	horizon     = 30 * 24 * time.Hour
+	maxFailures = 10
+	// evictCandidates is how many random new addresses are weighed
+	// when one must make room.
+	evictCandidates = 4
+	// flushInterval bounds how often a persistent address manager
+	// writes its file.
+	flushInterval = time.Second
+)
+
+// Selector is implemented by address databases that learn from
+// connection outcomes and choose which addresses to dial.
+type Selector interface {
+	// AddFrom adds an address heard from the node at source.
+	AddFrom(a *address.Address, source string) error
+	// Attempt records a connection attempt to addr and Good a
+	// successful one.
+	Attempt(addr string)
+	Good(addr string)
+	// Select returns up to n addresses to dial, preferring ones in
+	// network groups other than those of the exclude addresses.
+	Select(n int, exclude []string) []*address.Address
+}
+
+type entry struct {
+	addr        *address.Address
+	source      string
+	tried       bool
+	bucket      int
+	slot        int
+	attempts    int
+	lastTry     time.Time
+	lastSuccess time.Time
+	// heard is when the address was last added, updated or connected
+	// to.
+	heard time.Time
+}
+
+func (e *entry) terrible(now time.Time) bool {
+	if now.Sub(e.lastTry) < time.Minute {
+		return false
+	}
+	if e.addr.Signed() && now.Sub(time.Unix(0, e.addr.Timestamp)) > horizon {
+		return true
+	}
+	return e.attempts >= maxFailures && now.Sub(e.lastSuccess) > 7*24*time.Hour
+}
+
+// AddrMan keeps addresses in two tables of fixed-size buckets: new for
+// addresses only heard about and tried for ones the node has connected
+// to. Where an address goes is decided by hashing it with a secret key
+// together with its network group and, for new addresses, the group of
+// the node that announced it. A single source can therefore only fill
+// a few buckets, and an attacker cannot choose which entries its
+// addresses collide with. A colliding address only evicts an entry
+// that has gone stale. Once limit addresses are stored, the worst of a
+// few random new addresses makes room for the next one.
+//
+// An AddrMan is safe for concurrent use. It stores and hands out
+// copies of address records.
+type AddrMan struct {
+	limit   int
+	key     []byte
+	newTbl  [][]*entry
+	tried   [][]*entry
+	entries map[string]*entry
+	rng     *mrand.Rand
+	persist *store.Persister
+	sync.Mutex
+}
+
+// NewAddrMan returns an address manager holding up to about limit
+// addresses, a quarter of them tried.
+func NewAddrMan(limit int) *AddrMan {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return newAddrMan(limit, key)
+}
+
+// NewAddrManSeeded returns an address manager whose bucket key, and so
+// where addresses land and the order Select draws them in, is derived
+// from seed. It is meant for tests, which need placement to be
+// reproducible; a node must use a secret key.
+func NewAddrManSeeded(limit int, seed int64) *AddrMan {
+	return newAddrMan(limit, seedKey(seed))
+}
+
+func seedKey(seed int64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(seed))
+	key := sha256.Sum256(b[:])
+	return key[:]
+}
+
+func newAddrMan(limit int, key []byte) *AddrMan {
+	nTried := (limit/4 + bucketSize - 1) / bucketSize
+	nNew := (limit - nTried*bucketSize + bucketSize - 1) / bucketSize
+	if nTried < 1 {
+		nTried = 1
+	}
+	if nNew < 1 {
+		nNew = 1
+	}
+	am := &AddrMan{
+		limit:   limit,
+		key:     key,
+		newTbl:  make([][]*entry, nNew),
+		tried:   make([][]*entry, nTried),
+		entries: make(map[string]*entry),
+		rng:     mrand.New(mrand.NewSource(int64(binary.BigEndian.Uint64(key)))),
+	}
+	for i := range am.newTbl {
+		am.newTbl[i] = make([]*entry, bucketSize)
+	}
+	for i := range am.tried {
+		am.tried[i] = make([]*entry, bucketSize)
+	}
+	return am
+}
+
+type entryRecord struct {
+	Addr        *address.Address
+	Source      string
+	Tried       bool
+	Attempts    int
+	LastTry     time.Time
+	LastSuccess time.Time
+	Heard       time.Time
+}
+
+type addrManState struct {
+	Key     []byte
+	Entries []entryRecord
+}
+
+// OpenAddrMan returns an address manager kept in the file at path,
+// loading the addresses saved there.
+func OpenAddrMan(path string, limit int) (*AddrMan, error) {
+	return openAddrMan(path, NewAddrMan(limit))
+}
+
+// OpenAddrManSeeded is OpenAddrMan with the bucket key of a new file
+// derived from seed, as with NewAddrManSeeded. A key already saved in
+// the file is kept.
+func OpenAddrManSeeded(path string, limit int, seed int64) (*AddrMan, error) {
+	return openAddrMan(path, NewAddrManSeeded(limit, seed))
+}
+
+func openAddrMan(path string, am *AddrMan) (*AddrMan, error) {
+	var st addrManState
+	if err := store.Load(path, &st); err != nil {
+		return nil, err
+	}
+	// Keep the bucket key so addresses land where they were.
+	if len(st.Key) == len(am.key) {
+		am.key = st.Key
+		am.rng = mrand.New(mrand.NewSource(int64(binary.BigEndian.Uint64(am.key))))
+	}
+	now := time.Now()
+	for _, r := range st.Entries {
+		if r.Addr == nil || am.entries[r.Addr.Addr] != nil {
+			continue
+		}
+		e := &entry{
+			addr:        r.Addr,
+			source:      r.Source,
+			attempts:    r.Attempts,
+			lastTry:     r.LastTry,
+			lastSuccess: r.LastSuccess,
+			heard:       r.Heard,
+		}
+		if e.heard.IsZero() {
+			e.heard = now
+		}
+		if r.Tried {
+			b := am.triedBucket(e.addr.Addr)
+			s := am.slot(true, b, e.addr.Addr)
+			if am.tried[b][s] == nil {
+				e.tried, e.bucket, e.slot = true, b, s
+				am.tried[b][s] = e
+				am.entries[e.addr.Addr] = e
+				continue
+			}
+		}
+		_ = am.placeNew(e, now)
+	}
+	am.persist = store.NewPersister(path, flushInterval, am.state)
+	return am, nil
+}
+
+func (am *AddrMan) state() interface{} {
+	am.Lock()
+	defer am.Unlock()
+	st := addrManState{Key: am.key}
+	for _, e := range am.entries {
+		a := *e.addr
+		st.Entries = append(st.Entries, entryRecord{
+			Addr:        &a,
+			Source:      e.source,
+			Tried:       e.tried,
+			Attempts:    e.attempts,
+			LastTry:     e.lastTry,
+			LastSuccess: e.lastSuccess,
+			Heard:       e.heard,
+		})
+	}
+	return st
+}
+
+func (am *AddrMan) changed() {
+	if am.persist != nil {
+		am.persist.Dirty()
+	}
+}
+
+// Close writes a persistent address manager's file a last time.
+func (am *AddrMan) Close() error {
+	if am.persist == nil {
+		return nil
+	}
+	return am.persist.Close()
+}
+
+// Discard stops a persistent address manager without writing its file.
+func (am *AddrMan) Discard() {
+	if am.persist != nil {
+		am.persist.Discard()
+	}
+}
+
+func (am *AddrMan) hash(parts ...string) int {
+	h := sha256.New()
+	h.Write(am.key)
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return int(binary.BigEndian.Uint64(h.Sum(nil)) >> 1)
+}
+
+func group(addr string) string {
+	hp, err := netaddr.Parse(addr)
+	if err != nil {
+		return addr
+	}
+	return hp.Group()
+}
+
+func (am *AddrMan) newBucket(addr, source string) int {
+	h := am.hash("new", group(addr), source) % bucketsPerGroup
+	return am.hash("new", source, strconv.Itoa(h)) % len(am.newTbl)
+}
+
+func (am *AddrMan) triedBucket(addr string) int {
+	h := am.hash("tried", addr) % bucketsPerGroup
+	return am.hash("tried", group(addr), strconv.Itoa(h)) % len(am.tried)
+}
+
+func (am *AddrMan) slot(tried bool, bucket int, addr string) int {
+	return am.hash("slot", strconv.FormatBool(tried), strconv.Itoa(bucket), addr) % bucketSize
+}
+
+func (am *AddrMan) table(tried bool) [][]*entry {
+	if tried {
+		return am.tried
+	}
+	return am.newTbl
+}
+
+func (am *AddrMan) remove(e *entry) {
+	am.table(e.tried)[e.bucket][e.slot] = nil
+	delete(am.entries, e.addr.Addr)
+	am.changed()
+}
+
+// placeNew puts e in its new bucket unless the slot holds an entry
+// that is not terrible.
+func (am *AddrMan) placeNew(e *entry, now time.Time) error {
+	b := am.newBucket(e.addr.Addr, e.source)
+	s := am.slot(false, b, e.addr.Addr)
+	if old := am.newTbl[b][s]; old != nil {
+		if !old.terrible(now) {
+			return errors.New("address bucket full")
+		}
+		am.remove(old)
+	}
+	if am.entries[e.addr.Addr] == nil && len(am.entries) >= am.limit && !am.evict(now) {
+		return errors.New("address list full")
+	}
+	e.tried, e.bucket, e.slot = false, b, s
+	am.newTbl[b][s] = e
+	am.entries[e.addr.Addr] = e
+	am.changed()
+	return nil
+}
+
+// evict removes one of a few new addresses drawn at random: a terrible
+// one if there is one, otherwise the one least recently heard of. It
+// reports whether there was a new address. Tried addresses are kept.
+func (am *AddrMan) evict(now time.Time) bool {
+	var news []*entry
+	for _, bucket := range am.newTbl {
+		for _, e := range bucket {
+			if e != nil {
+				news = append(news, e)
+			}
+		}
+	}
+	var worst *entry
+	for i := 0; i < evictCandidates && i < len(news); i++ {
+		j := i + am.rng.Intn(len(news)-i)
+		news[i], news[j] = news[j], news[i]
+		e := news[i]
+		if e.terrible(now) {
+			worst = e
+			break
+		}
+		if worst == nil || e.heard.Before(worst.heard) {
+			worst = e
+		}
+	}
+	if worst == nil {
+		return false
+	}
+	am.remove(worst)
+	return true
+}
+
+// Prune removes the addresses not heard of within maxAge, except those
+// keep reports true for, and returns how many it removed.
+func (am *AddrMan) Prune(maxAge time.Duration, keep func(string) bool) int {
+	am.Lock()
+	defer am.Unlock()
+	cutoff := time.Now().Add(-maxAge)
+	pruned := 0
+	for addr, e := range am.entries {
+		if e.heard.Before(cutoff) && (keep == nil || !keep(addr)) {
+			am.remove(e)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+func (am *AddrMan) Add(a *address.Address) error {
+	return am.AddFrom(a, a.Addr)
+}
+
+func (am *AddrMan) AddFrom(a *address.Address, source string) error {
+	am.Lock()
+	defer am.Unlock()
+	if am.entries[a.Addr] != nil {
+		return errors.New("address already exists")
+	}
+	now := time.Now()
+	return am.placeNew(&entry{addr: a.Copy(), source: group(source), heard: now}, now)
+}
+
+// Put adds a or replaces the record stored for its address, keeping
+// its place in the tables.
+func (am *AddrMan) Put(a *address.Address) error {
+	am.Lock()
+	defer am.Unlock()
+	now := time.Now()
+	if e := am.entries[a.Addr]; e != nil {
+		e.addr = a.Copy()
+		e.heard = now
+		am.changed()
+		return nil
+	}
+	return am.placeNew(&entry{addr: a.Copy(), source: group(a.Addr), heard: now}, now)
+}
+
+func (am *AddrMan) Get(addr string) *address.Address {
+	am.Lock()
+	defer am.Unlock()
+	if e := am.entries[addr]; e != nil {
+		return e.addr.Copy()
+	}
+	return nil
+}
+
+func (am *AddrMan) UpdateLastSeen(addr string, lastSeen int64) error {
+	am.Lock()
+	defer am.Unlock()
+	e := am.entries[addr]
+	if e == nil {
+		return errors.New("address not found")
+	}
+	e.addr.LastSeen = lastSeen
+	e.heard = time.Now()
+	am.changed()
+	return nil
+}
+
+func (am *AddrMan) List() []*address.Address {
+	am.Lock()
+	defer am.Unlock()
+	addresses := make([]*address.Address, 0, len(am.entries))
+	for _, e := range am.entries {
+		addresses = append(addresses, e.addr.Copy())
+	}
+	return addresses
+}
+
+func (am *AddrMan) Serialize() []*proto.Address {
+	am.Lock()
+	defer am.Unlock()
+	addresses := make([]*proto.Address, 0, len(am.entries))
+	for _, e := range am.entries {
+		addresses = append(addresses, e.addr.Serialize())
+	}
+	return addresses
+}
+
+func (am *AddrMan) Attempt(addr string) {
+	am.Lock()
+	defer am.Unlock()
+	if e := am.entries[addr]; e != nil {
+		e.attempts++
+		e.lastTry = time.Now()
+		am.changed()
+	}
+}
+
+// Good moves addr to the tried table. The entry it collides with
+// there, if any, goes back to the new table.
+func (am *AddrMan) Good(addr string) {
+	am.Lock()
+	defer am.Unlock()
+	e := am.entries[addr]
+	if e == nil {
+		return
+	}
+	now := time.Now()
+	e.attempts = 0
+	e.lastTry = now
+	e.lastSuccess = now
+	e.heard = now
+	am.changed()
+	if e.tried {
+		return
+	}
+	am.remove(e)
+	b := am.triedBucket(addr)
+	s := am.slot(true, b, addr)
+	if old := am.tried[b][s]; old != nil {
+		am.remove(old)
+		// Make room in the new table for the tried address it
+		// displaced; that address has worked before.
+		nb := am.newBucket(old.addr.Addr, old.source)
+		ns := am.slot(false, nb, old.addr.Addr)
+		if prev := am.newTbl[nb][ns]; prev != nil {
+			am.remove(prev)
+		}
+		_ = am.placeNew(old, now)
+	}
+	e.tried, e.bucket, e.slot = true, b, s
+	am.tried[b][s] = e
+	am.entries[addr] = e
+}
+
+// Select draws from the tried and new tables with equal odds, so the
+// fewer tried addresses are each far likelier to be picked. Terrible
+// addresses are skipped, and addresses whose group was already picked
+// or excluded only fill the remaining places.
+func (am *AddrMan) Select(n int, exclude []string) []*address.Address {
+	am.Lock()
+	defer am.Unlock()
+	now := time.Now()
+	var tried, fresh []*entry
+	for _, e := range am.entries {
+		if e.terrible(now) {
+			continue
+		}
+		if e.tried {
+			tried = append(tried, e)
+		} else {
+			fresh = append(fresh, e)
+		}
+	}
+	am.rng.Shuffle(len(tried), func(i, j int) { tried[i], tried[j] = tried[j], tried[i] })
+	am.rng.Shuffle(len(fresh), func(i, j int) { fresh[i], fresh[j] = fresh[j], fresh[i] })
+	var order []*entry
+	for len(tried) > 0 || len(fresh) > 0 {
+		if len(fresh) == 0 || (len(tried) > 0 && am.rng.Intn(2) == 0) {
+			order, tried = append(order, tried[0]), tried[1:]
+		} else {
+			order, fresh = append(order, fresh[0]), fresh[1:]
+		}
+	}
+
+	groups := make(map[string]bool)
+	for _, a := range exclude {
+		groups[group(a)] = true
+	}
+	var picked []*address.Address
+	var rest []*address.Address
+	for _, e := range order {
+		g := group(e.addr.Addr)
+		if groups[g] {
+			rest = append(rest, e.addr.Copy())
+			continue
+		}
+		if len(picked) < n {
+			groups[g] = true
+			picked = append(picked, e.addr.Copy())
+		}
+	}
+	for _, a := range rest {
+		if len(picked) >= n {
+			break
+		}
+		picked = append(picked, a)
+	}
+	return picked
+}
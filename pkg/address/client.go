@@ -2,15 +2,48 @@ package address
 
 import (
 	"finalbruh/pkg/proto"
-	"fmt"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"time"
 )
 
+// RPCTimeout bounds each attempt of an RPC without a timeout of its
+// own.
 const RPCTimeout = 2 * time.Second
 
-func clientUnaryInterceptor(
+// DefaultTimeouts returns the per-attempt timeouts used by a new
+// ConnManager for RPCs that may take longer than RPCTimeout, keyed by
+// full method name.
+func DefaultTimeouts() map[string]time.Duration {
+	return map[string]time.Duration{
+		// The receiver waits for handshakes with the new members.
+		"/BrunoCoin/AddMember": 5 * time.Second,
+		"/BrunoCoin/Register":  10 * time.Second,
+	}
+}
+
+// SetTimeouts replaces the per-attempt timeouts: def for RPCs without
+// an entry in perMethod, keyed by full method name. A timeout of 0
+// leaves attempts bounded only by the caller's context.
+func (m *ConnManager) SetTimeouts(def time.Duration, perMethod map[string]time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.timeout = def
+	m.timeouts = perMethod
+}
+
+func (m *ConnManager) timeoutFor(method string) time.Duration {
+	m.Lock()
+	defer m.Unlock()
+	if d, ok := m.timeouts[method]; ok {
+		return d
+	}
+	return m.timeout
+}
+
+// timeoutInterceptor bounds each attempt of an RPC by its timeout. A
+// caller's earlier deadline still applies.
+func (m *ConnManager) timeoutInterceptor(
 	ctx context.Context,
 	method string,
 	req, reply interface{},
@@ -18,136 +51,152 @@ func clientUnaryInterceptor(
 	invoker grpc.UnaryInvoker,
 	opts ...grpc.CallOption,
 ) error {
-	ctx, cancel := context.WithTimeout(ctx, RPCTimeout)
-	defer cancel()
+	if d := m.timeoutFor(method); d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
 	return invoker(ctx, method, req, reply, cc, opts...)
 }
 
-func connectToServer(addr string) (*grpc.ClientConn, error) {
-	return grpc.Dial(addr, []grpc.DialOption{
-		grpc.WithInsecure(),
-		grpc.FailOnNonTempDialError(true),
-		grpc.WithUnaryInterceptor(clientUnaryInterceptor),
-	}...)
+func (a *Address) GetConnection(cm *ConnManager) (proto.BrunoCoinClient, error) {
+	return cm.Get(a.Addr)
 }
 
-func (a *Address) GetConnection() (proto.BrunoCoinClient, *grpc.ClientConn, error) {
-	cc, err := connectToServer(a.Addr)
+func (a *Address) VersionRPC(ctx context.Context, cm *ConnManager, request *proto.VersionRequest) (*proto.VersionReply, error) {
+	c, err := a.GetConnection(cm)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return proto.NewBrunoCoinClient(cc), cc, err
+	return c.Version(ctx, request)
 }
 
-func (a *Address) VersionRPC(request *proto.VersionRequest) (*proto.Empty, error) {
-	c, cc, err := a.GetConnection()
+func (a *Address) VerAckRPC(ctx context.Context, cm *ConnManager, request *proto.VersionAck) (*proto.Empty, error) {
+	c, err := a.GetConnection(cm)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		err := cc.Close()
-		if err != nil {
-			fmt.Printf("ERROR {Address.VersionRPC}: " +
-				"error when closing connection")
-		}
-	}()
-	reply, err := c.Version(context.Background(), request)
-	a.SentVer = time.Now()
-	return reply, err
+	return c.VerAck(ctx, request)
 }
 
-func (a *Address) GetAddressesRPC(request *proto.Empty) (*proto.Addresses, error) {
-	c, cc, err := a.GetConnection()
+func (a *Address) DialBackRPC(ctx context.Context, cm *ConnManager, request *proto.DialBackRequest) (*proto.DialBackReply, error) {
+	c, err := a.GetConnection(cm)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		err := cc.Close()
-		if err != nil {
-			fmt.Printf("ERROR {Address.GetAddressesRPC}: " +
-				"error when closing connection")
-		}
-	}()
-	reply, err := c.GetAddresses(context.Background(), request)
-	return reply, err
+	return c.DialBack(ctx, request)
 }
 
-func (a *Address) SendAddressesRPC(request *proto.Addresses) (*proto.Empty, error) {
-	c, cc, err := a.GetConnection()
+func (a *Address) GetAddressesRPC(ctx context.Context, cm *ConnManager, request *proto.GetAddressesRequest) (*proto.Addresses, error) {
+	c, err := a.GetConnection(cm)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		err := cc.Close()
-		if err != nil {
-			fmt.Printf("ERROR {Address.SendAddressesRPC}: " +
-				"error when closing connection")
-		}
-	}()
-	reply, err := c.SendAddresses(context.Background(), request)
-	return reply, err
+	return c.GetAddresses(ctx, request)
 }
 
-func (a *Address) RegisterRPC(request *proto.Registration) (*proto.Certificate, error) {
-	c, cc, err := a.GetConnection()
+func (a *Address) ListAddressesRPC(ctx context.Context, cm *ConnManager, request *proto.ListAddressesRequest) (*proto.AddressPage, error) {
+	c, err := a.GetConnection(cm)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		err := cc.Close()
+	return c.ListAddresses(ctx, request)
+}
+
+// ListAllAddresses pages through every address known to the node at a.
+// The node only answers callers it authorized.
+func (a *Address) ListAllAddresses(ctx context.Context, cm *ConnManager) ([]*proto.Address, error) {
+	var addrs []*proto.Address
+	cursor := ""
+	for {
+		page, err := a.ListAddressesRPC(ctx, cm, &proto.ListAddressesRequest{Cursor: cursor})
 		if err != nil {
-			fmt.Printf("ERROR {Address.ForwardBlockRPC}: " +
-				"error when closing connection")
+			return nil, err
 		}
-	}()
-	reply, err := c.Register(context.Background(), request)
-	return reply, err
+		addrs = append(addrs, page.Addrs...)
+		if page.NextCursor == "" {
+			return addrs, nil
+		}
+		cursor = page.NextCursor
+	}
 }
 
-func (a *Address) AddMemberRPC(request *proto.EncKeysMem) (*proto.Empty, error) {
-	c, cc, err := a.GetConnection()
+func (a *Address) SendAddressesRPC(ctx context.Context, cm *ConnManager, request *proto.Addresses) (*proto.Empty, error) {
+	c, err := a.GetConnection(cm)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		err := cc.Close()
-		if err != nil {
-			fmt.Printf("ERROR {Address.ForwardBlockRPC}: " +
-				"error when closing connection")
-		}
-	}()
-	reply, err := c.AddMember(context.Background(), request)
-	return reply, err
+	return c.SendAddresses(ctx, request)
 }
 
-func (a *Address) KickMemberRPC(request *proto.EncKeysMem) (*proto.Empty, error) {
-	c, cc, err := a.GetConnection()
+func (a *Address) RegisterRPC(ctx context.Context, cm *ConnManager, request *proto.Registration) (*proto.Certificate, error) {
+	c, err := a.GetConnection(cm)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		err := cc.Close()
-		if err != nil {
-			fmt.Printf("ERROR {Address.ForwardBlockRPC}: " +
-				"error when closing connection")
-		}
-	}()
-	reply, err := c.KickMember(context.Background(), request)
-	return reply, err
+	return c.Register(ctx, request)
 }
 
-func (a *Address) GroupMessageRPC(request *proto.GroupIM) (*proto.Empty, error) {
-	c, cc, err := a.GetConnection()
+func (a *Address) AddMemberRPC(ctx context.Context, cm *ConnManager, request *proto.EncKeysMem) (*proto.Empty, error) {
+	c, err := a.GetConnection(cm)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		err := cc.Close()
-		if err != nil {
-			fmt.Printf("ERROR {Address.ForwardBlockRPC}: " +
-				"error when closing connection")
-		}
-	}()
-	reply, err := c.GroupMessage(context.Background(), request)
-	return reply, err
+	return c.AddMember(ctx, request)
+}
+
+func (a *Address) KickMemberRPC(ctx context.Context, cm *ConnManager, request *proto.EncKeysMem) (*proto.Empty, error) {
+	c, err := a.GetConnection(cm)
+	if err != nil {
+		return nil, err
+	}
+	return c.KickMember(ctx, request)
+}
+
+func (a *Address) GroupMessageRPC(ctx context.Context, cm *ConnManager, request *proto.GroupIM) (*proto.Empty, error) {
+	c, err := a.GetConnection(cm)
+	if err != nil {
+		return nil, err
+	}
+	return c.GroupMessage(ctx, request)
+}
+
+func (a *Address) PingRPC(ctx context.Context, cm *ConnManager, request *proto.PingRequest) (*proto.Ack, error) {
+	c, err := a.GetConnection(cm)
+	if err != nil {
+		return nil, err
+	}
+	return c.Ping(ctx, request)
+}
+
+func (a *Address) PingReqRPC(ctx context.Context, cm *ConnManager, request *proto.PingReqRequest) (*proto.Ack, error) {
+	c, err := a.GetConnection(cm)
+	if err != nil {
+		return nil, err
+	}
+	return c.PingReq(ctx, request)
+}
+
+func (a *Address) FindNodeRPC(ctx context.Context, cm *ConnManager, request *proto.FindRequest) (*proto.FindNodeReply, error) {
+	c, err := a.GetConnection(cm)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindNode(ctx, request)
+}
+
+func (a *Address) FindValueRPC(ctx context.Context, cm *ConnManager, request *proto.FindRequest) (*proto.FindValueReply, error) {
+	c, err := a.GetConnection(cm)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindValue(ctx, request)
+}
+
+func (a *Address) StoreRPC(ctx context.Context, cm *ConnManager, request *proto.StoreRequest) (*proto.Empty, error) {
+	c, err := a.GetConnection(cm)
+	if err != nil {
+		return nil, err
+	}
+	return c.Store(ctx, request)
 }
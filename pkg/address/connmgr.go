@@ -0,0 +1,244 @@
+package address
+
+import (
+	"errors"
+	"finalbruh/pkg/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+// ConnManager caches one grpc.ClientConn per peer address so that
+// RPCs to the same peer share a single TCP/HTTP2 connection. A
+// connection is only closed while no RPC is using it: one that is
+// dropped, evicted or has failed while busy is closed once its last
+// RPC returns.
+type ConnManager struct {
+	conns map[string]*managedConn
+	limit int
+	idle  time.Duration
+	opts  []grpc.DialOption
+	retry map[string]RetryPolicy
+
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+
+	closed bool
+	done   chan struct{}
+	sync.Mutex
+}
+
+type managedConn struct {
+	cc       *grpc.ClientConn
+	lastUsed time.Time
+	// busy counts the RPCs using the connection. A retired connection
+	// is no longer handed out and is closed once it is not busy.
+	busy    int
+	retired bool
+}
+
+// NewConnManager returns a manager holding at most limit open
+// connections, not counting busy ones that could not be evicted.
+// Connections unused for longer than idle are closed by a background
+// sweep; an idle of 0 disables the sweep. Connections are secured with
+// creds, or unencrypted if creds is nil, and opts are applied to every
+// dial. RPCs are retried according to DefaultRetryPolicies, each
+// attempt bounded by DefaultTimeouts or RPCTimeout.
+func NewConnManager(limit int, idle time.Duration, creds credentials.TransportCredentials, opts ...grpc.DialOption) *ConnManager {
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	m := &ConnManager{
+		conns: make(map[string]*managedConn),
+		limit: limit,
+		idle:  idle,
+		opts: []grpc.DialOption{
+			grpc.WithTransportCredentials(creds),
+			grpc.FailOnNonTempDialError(true),
+		},
+		retry:    DefaultRetryPolicies(),
+		timeout:  RPCTimeout,
+		timeouts: DefaultTimeouts(),
+		done:     make(chan struct{}),
+	}
+	m.opts = append(m.opts, grpc.WithChainUnaryInterceptor(m.retryInterceptor, m.timeoutInterceptor))
+	m.opts = append(m.opts, opts...)
+	if idle > 0 {
+		go m.sweep()
+	}
+	return m
+}
+
+// Get returns a client for addr, dialing a new connection if there
+// is no usable cached one. Each RPC made with the client uses the
+// connection cached at the time, redialing if it has been closed.
+func (m *ConnManager) Get(addr string) (proto.BrunoCoinClient, error) {
+	mc, err := m.acquire(addr)
+	if err != nil {
+		return nil, err
+	}
+	m.release(mc)
+	return proto.NewBrunoCoinClient(&handle{m: m, addr: addr}), nil
+}
+
+// acquire returns the connection to addr, dialing one if needed, and
+// marks it busy until release.
+func (m *ConnManager) acquire(addr string) (*managedConn, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.closed {
+		return nil, errors.New("connection manager closed")
+	}
+	mc := m.conns[addr]
+	if mc != nil {
+		switch mc.cc.GetState() {
+		case connectivity.Shutdown, connectivity.TransientFailure:
+			// RPCs on a failed connection fail fast until its backoff
+			// expires, so redial in case the peer has come back.
+			m.retire(addr, mc)
+			mc = nil
+		}
+	}
+	if mc == nil {
+		if m.limit > 0 && len(m.conns) >= m.limit {
+			m.evictOldest()
+		}
+		cc, err := grpc.Dial(addr, m.opts...)
+		if err != nil {
+			return nil, err
+		}
+		mc = &managedConn{cc: cc}
+		m.conns[addr] = mc
+	}
+	mc.busy++
+	mc.lastUsed = time.Now()
+	return mc, nil
+}
+
+func (m *ConnManager) release(mc *managedConn) {
+	m.Lock()
+	defer m.Unlock()
+	mc.busy--
+	mc.lastUsed = time.Now()
+	if mc.retired && mc.busy == 0 {
+		_ = mc.cc.Close()
+	}
+}
+
+// retire forgets the connection to addr and closes it unless it is
+// busy, in which case the last RPC using it does. The caller must hold
+// the lock.
+func (m *ConnManager) retire(addr string, mc *managedConn) {
+	if m.conns[addr] == mc {
+		delete(m.conns, addr)
+	}
+	if mc.retired {
+		return
+	}
+	mc.retired = true
+	if mc.busy == 0 {
+		_ = mc.cc.Close()
+	}
+}
+
+// handle is the connection a client returned by Get uses: it makes
+// each RPC on the connection currently cached for addr.
+type handle struct {
+	m    *ConnManager
+	addr string
+}
+
+func (h *handle) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	mc, err := h.m.acquire(h.addr)
+	if err != nil {
+		return err
+	}
+	defer h.m.release(mc)
+	return mc.cc.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream is not supported: the service has no streaming RPCs.
+func (h *handle) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Error(codes.Unimplemented, "streams are not supported")
+}
+
+// Drop forgets the connection to addr, if any, closing it once it is
+// not busy.
+func (m *ConnManager) Drop(addr string) {
+	m.Lock()
+	defer m.Unlock()
+	if mc := m.conns[addr]; mc != nil {
+		m.retire(addr, mc)
+	}
+}
+
+// Len returns the number of open connections.
+func (m *ConnManager) Len() int {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.conns)
+}
+
+// Close closes every cached connection, busy or not, and stops the
+// idle sweep. Subsequent calls to Get fail.
+func (m *ConnManager) Close() error {
+	m.Lock()
+	defer m.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	var firstErr error
+	for addr, mc := range m.conns {
+		mc.retired = true
+		if err := mc.cc.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(m.conns, addr)
+	}
+	return firstErr
+}
+
+// evictOldest retires the least recently used connection that is not
+// busy, if any. The caller must hold the lock.
+func (m *ConnManager) evictOldest() {
+	var oldest string
+	var oldestT time.Time
+	for addr, mc := range m.conns {
+		if mc.busy > 0 {
+			continue
+		}
+		if oldest == "" || mc.lastUsed.Before(oldestT) {
+			oldest, oldestT = addr, mc.lastUsed
+		}
+	}
+	if oldest != "" {
+		m.retire(oldest, m.conns[oldest])
+	}
+}
+
+func (m *ConnManager) sweep() {
+	ticker := time.NewTicker(m.idle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.Lock()
+			for addr, mc := range m.conns {
+				if mc.busy == 0 && now.Sub(mc.lastUsed) > m.idle {
+					m.retire(addr, mc)
+				}
+			}
+			m.Unlock()
+		}
+	}
+}
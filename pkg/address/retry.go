@@ -0,0 +1,157 @@
+package address
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	mrand "math/rand"
+	"time"
+)
+
+// IdempotencyKey is the metadata key carrying the key shared by every
+// attempt of a retried RPC that is not idempotent. The receiver uses it
+// to apply the RPC only once.
+const IdempotencyKey = "x-idempotency-key"
+
+// RetryPolicy controls how often an RPC is attempted.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first. A
+	// value below 2 disables retries.
+	MaxAttempts int
+	// The wait before the second attempt is Backoff, doubling for
+	// every further one up to MaxBackoff. Each wait is then randomized
+	// by up to Jitter of itself in either direction.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Jitter     float64
+	// Retryable lists the status codes worth another attempt.
+	Retryable []codes.Code
+	// Idempotent RPCs may be applied more than once. Retries of the
+	// others carry an idempotency key.
+	Idempotent bool
+}
+
+var transient = []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.Aborted}
+
+// DefaultRetryPolicies returns the policies used by a new ConnManager,
+// keyed by full method name. Methods without a policy are attempted
+// once: the handshake and the failure detector's probes do their own
+// retrying.
+func DefaultRetryPolicies() map[string]RetryPolicy {
+	lookup := RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     100 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		Jitter:      0.2,
+		Retryable:   transient,
+		Idempotent:  true,
+	}
+	// Losing a group update leaves a member with a stale key, so these
+	// are retried harder.
+	group := RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     100 * time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+		Jitter:      0.2,
+		Retryable:   transient,
+	}
+	register := group
+	register.MaxAttempts = 3
+	return map[string]RetryPolicy{
+		"/BrunoCoin/GetAddresses":  lookup,
+		"/BrunoCoin/ListAddresses": lookup,
+		"/BrunoCoin/SendAddresses": lookup,
+		"/BrunoCoin/FindNode":      lookup,
+		"/BrunoCoin/FindValue":     lookup,
+		"/BrunoCoin/Store":         lookup,
+		"/BrunoCoin/Register":      register,
+		"/BrunoCoin/AddMember":     group,
+		"/BrunoCoin/KickMember":    group,
+		"/BrunoCoin/GroupMessage":  group,
+	}
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	c := status.Code(err)
+	for _, r := range p.Retryable {
+		if c == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay returns the wait after the given failed attempt.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((2*mrand.Float64() - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// SetRetryPolicies replaces the retry policies, keyed by full method
+// name. A nil map disables retries.
+func (m *ConnManager) SetRetryPolicies(policies map[string]RetryPolicy) {
+	m.Lock()
+	defer m.Unlock()
+	m.retry = policies
+}
+
+func (m *ConnManager) policy(method string) RetryPolicy {
+	m.Lock()
+	defer m.Unlock()
+	return m.retry[method]
+}
+
+// retryInterceptor attempts an RPC as often as its policy allows, or
+// until the caller's context is done. It runs before
+// timeoutInterceptor so every attempt gets the full timeout.
+func (m *ConnManager) retryInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	p := m.policy(method)
+	if p.MaxAttempts > 1 && !p.Idempotent {
+		key, err := newIdempotencyKey()
+		if err != nil {
+			return err
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, IdempotencyKey, key)
+	}
+	for attempt := 1; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		t := time.NewTimer(p.Delay(attempt))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+	}
+}
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,15 +1,169 @@
 package pkg
 
 import (
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/dht"
+	"finalbruh/pkg/faults"
+	"finalbruh/pkg/netaddr"
+	"finalbruh/pkg/transport"
+	"fmt"
+	"os"
+	"strconv"
 	"time"
 )
 
 type Config struct {
 	Version    int
-	PeerLimit  int
 	AddrLimit  int
 	Port       int
 	VerTimeout time.Duration
+
+	// PeerLimit caps the peers the node dials and InboundLimit the
+	// peers that dial it. When the inbound slots are full, a new
+	// inbound peer is only accepted in place of a worse one.
+	PeerLimit    int
+	InboundLimit int
+
+	// ListenAddr is the host:port the server binds to. It defaults to
+	// every interface on Port; a port of 0 picks a free port.
+	ListenAddr string
+	// AdvertiseAddr is the host:port other nodes are told to dial. It
+	// defaults to the listen host, or the machine's host name when
+	// listening on every interface, together with the bound port.
+	AdvertiseAddr string
+
+	// ConnLimit caps the number of cached outbound connections and
+	// ConnIdleTimeout closes connections that have not been used
+	// for that long. Busy connections are never closed. ConnLimit
+	// should leave room for PeerLimit and InboundLimit together, or
+	// the connections to peers are redialed over and over.
+	ConnLimit       int
+	ConnIdleTimeout time.Duration
+
+	// RetryPolicies says how often outbound RPCs are attempted, keyed
+	// by full method name; a nil map disables retries. Retries that
+	// are not idempotent carry a key, and an RPC repeating a key seen
+	// from the same caller within IdempotencyWindow is answered with
+	// the first reply instead of being applied again.
+	RetryPolicies     map[string]address.RetryPolicy
+	IdempotencyWindow time.Duration
+
+	// Each attempt of an outbound RPC times out after its entry in
+	// RPCTimeouts, keyed by full method name, or after RPCTimeout.
+	// A caller's earlier deadline still applies.
+	RPCTimeout  time.Duration
+	RPCTimeouts map[string]time.Duration
+
+	// Insecure disables mutual TLS between nodes. It exists for tests
+	// and must not be used otherwise.
+	Insecure bool
+	// TrustedCA, if set, is the encoded public key of the CA that must
+	// have issued the certificate of every peer.
+	TrustedCA string
+
+	// Transport carries the node's connections. It defaults to TCP;
+	// tests may use transport.NewMemory() to run without sockets.
+	Transport transport.Transport
+
+	// PeerRate and GlobalRate limit inbound RPCs per second from a
+	// single peer and from all peers together, allowing bursts of up
+	// to PeerBurst and GlobalBurst. HostRate and HostBurst limit all
+	// peers on one host, so a peer gains nothing by changing keys. A
+	// rate of 0 disables the limit.
+	PeerRate    float64
+	PeerBurst   int
+	HostRate    float64
+	HostBurst   int
+	GlobalRate  float64
+	GlobalBurst int
+	// MaxMsgSize bounds the size in bytes of an inbound message and
+	// MaxAddrsPerMsg the number of addresses in one SendAddresses.
+	MaxMsgSize     int
+	MaxAddrsPerMsg int
+	// MaxGossipDials bounds how many handshakes with addresses learned
+	// through SendAddresses may be in flight at once.
+	MaxGossipDials int
+	// Messages to other nodes are sent by at most SendWorkers workers
+	// from a queue per recipient holding at most SendQueueLimit
+	// messages. A sender waits while the recipient's queue is full.
+	SendWorkers    int
+	SendQueueLimit int
+	// AcceptUnsignedAddrs lets the node store and relay address records
+	// that were not signed by the node they describe, as sent by nodes
+	// predating signed announcements.
+	AcceptUnsignedAddrs bool
+
+	// BanThreshold is the misbehavior score at which a peer is banned
+	// for BanDuration. Misbehavior also counts against the peer's
+	// host, which is banned at HostBanThreshold. A threshold of 0
+	// disables automatic bans. A score drops by one point every
+	// ScoreDecay, so occasional faults are forgiven; 0 keeps scores
+	// until a ban.
+	BanThreshold     int
+	HostBanThreshold int
+	BanDuration      time.Duration
+	ScoreDecay       time.Duration
+
+	// Failure detection: one peer is probed every ProbeInterval, and
+	// a peer that misses a direct probe and IndirectProbes indirect
+	// ones within ProbeTimeout is suspected, then declared dead and
+	// evicted after SuspicionTimeout. A ProbeInterval of 0 disables
+	// probing.
+	ProbeInterval    time.Duration
+	ProbeTimeout     time.Duration
+	IndirectProbes   int
+	SuspicionTimeout time.Duration
+
+	// Seeds are dialed on Start to join the network. Afterwards the
+	// node tops its peers up towards PeerLimit every
+	// MaintenanceInterval and re-announces its address every
+	// BroadcastInterval. An interval of 0 disables its task, here and
+	// for RepublishInterval and AddrPruneInterval.
+	Seeds               []string
+	MaintenanceInterval time.Duration
+	BroadcastInterval   time.Duration
+
+	// StaticPeers are kept connected for as long as the node runs. A
+	// lost static peer is dialed again after a backoff that doubles from
+	// StaticRetryMin up to StaticRetryMax, and the handshake with a
+	// connected one is repeated every StaticRefreshInterval so that it
+	// learns of the node again after a restart. Static peers do not
+	// count towards PeerLimit or InboundLimit.
+	StaticPeers           []string
+	StaticRetryMin        time.Duration
+	StaticRetryMax        time.Duration
+	StaticRefreshInterval time.Duration
+
+	// The DHT maps key fingerprints to addresses. Lookups query
+	// DHTAlpha nodes at a time and settle on the DHTBucketSize closest
+	// to the key; the node republishes its own record every
+	// RepublishInterval. It stores up to DHTRecordLimit records for
+	// the keys it is among the closest nodes to.
+	DHTBucketSize     int
+	DHTAlpha          int
+	DHTRecordLimit    int
+	RepublishInterval time.Duration
+
+	// GetAddresses answers with a random sample of at most
+	// AddrSampleSize addresses. Only the nodes whose key fingerprints
+	// are in AddrListers may page through the whole table with
+	// ListAddresses, AddrPageSize addresses at a time.
+	AddrSampleSize int
+	AddrPageSize   int
+	AddrListers    []string
+
+	// Addresses not heard of for AddrMaxAge are pruned every
+	// AddrPruneInterval, unless they belong to a peer.
+	AddrMaxAge        time.Duration
+	AddrPruneInterval time.Duration
+
+	// DataDir is where the address and peer databases are kept across
+	// restarts. If empty they only live in memory.
+	DataDir string
+
+	// Faults, if set, injects drops, delays, duplicates, reordering
+	// and partitions into the node's RPCs. For tests only.
+	Faults *faults.Network
 }
 
 func DefaultConfig(port int) *Config {
@@ -19,6 +173,97 @@ func DefaultConfig(port int) *Config {
 		AddrLimit:  1000,
 		Port:       port,
 		VerTimeout: time.Second * 2,
+
+		InboundLimit: 100,
+
+		ConnLimit:       128,
+		ConnIdleTimeout: time.Minute * 5,
+
+		RetryPolicies:     address.DefaultRetryPolicies(),
+		IdempotencyWindow: time.Minute * 10,
+
+		RPCTimeout:  address.RPCTimeout,
+		RPCTimeouts: address.DefaultTimeouts(),
+
+		Transport: transport.TCP{},
+
+		PeerRate:       50,
+		PeerBurst:      100,
+		HostRate:       200,
+		HostBurst:      400,
+		GlobalRate:     500,
+		GlobalBurst:    1000,
+		MaxMsgSize:     1 << 20,
+		MaxAddrsPerMsg: 1000,
+		MaxGossipDials: 8,
+		SendWorkers:    16,
+		SendQueueLimit: 64,
+
+		BanThreshold:     100,
+		HostBanThreshold: 400,
+		BanDuration:      24 * time.Hour,
+		ScoreDecay:       time.Minute,
+
+		ProbeInterval:    time.Second,
+		ProbeTimeout:     500 * time.Millisecond,
+		IndirectProbes:   3,
+		SuspicionTimeout: 5 * time.Second,
+
+		MaintenanceInterval: 30 * time.Second,
+		BroadcastInterval:   10 * time.Minute,
+
+		StaticRetryMin:        time.Second,
+		StaticRetryMax:        time.Minute,
+		StaticRefreshInterval: time.Minute,
+
+		DHTBucketSize:     dht.DefaultK,
+		DHTAlpha:          dht.DefaultAlpha,
+		DHTRecordLimit:    dht.DefaultRecordLimit,
+		RepublishInterval: time.Hour,
+
+		AddrSampleSize: 100,
+		AddrPageSize:   500,
+
+		AddrMaxAge:        7 * 24 * time.Hour,
+		AddrPruneInterval: time.Hour,
 	}
 	return c
 }
+
+func (c *Config) listenAddr() (netaddr.HostPort, error) {
+	if c.ListenAddr == "" {
+		return netaddr.Parse(":" + strconv.Itoa(c.Port))
+	}
+	return netaddr.Parse(c.ListenAddr)
+}
+
+// advertiseAddr returns the address peers should dial given the
+// address the server is actually bound to.
+func (c *Config) advertiseAddr(bound netaddr.HostPort) (netaddr.HostPort, error) {
+	if c.AdvertiseAddr != "" {
+		adv, err := netaddr.Parse(c.AdvertiseAddr)
+		if err != nil {
+			return adv, err
+		}
+		if adv.Port == 0 {
+			adv.Port = bound.Port
+		}
+		if !adv.Dialable() {
+			return adv, fmt.Errorf("advertised address %v is not dialable", c.AdvertiseAddr)
+		}
+		return adv, nil
+	}
+	listen, err := c.listenAddr()
+	if err != nil {
+		return listen, err
+	}
+	adv := netaddr.HostPort{Host: listen.Host, Port: bound.Port}
+	if listen.IsUnspecified() {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return adv, err
+		}
+		adv.Host = hostname
+	}
+	return adv, nil
+}
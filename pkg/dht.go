@@ -0,0 +1,279 @@
+package pkg
+
+import (
+	"errors"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/dht"
+	"finalbruh/pkg/group"
+	"finalbruh/pkg/peer"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/utils"
+	"golang.org/x/net/context"
+)
+
+// dhtClient sends the DHT's queries over the node's connections,
+// introducing the node as the sender of each.
+type dhtClient struct {
+	n *Node
+}
+
+func (c *dhtClient) sender() *proto.Contact {
+	return dht.Contact{ID: c.n.DHT.Self(), Addr: c.n.Addr}.Serialize()
+}
+
+func (c *dhtClient) FindNode(ctx context.Context, addr string, target dht.NodeID) ([]dht.Contact, error) {
+	reply, err := address.New(addr, 0).FindNodeRPC(ctx, c.n.Conns, &proto.FindRequest{
+		Sender: c.sender(),
+		Target: target[:],
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contacts(reply.Contacts), nil
+}
+
+func (c *dhtClient) FindValue(ctx context.Context, addr string, key dht.NodeID) (*dht.Record, []dht.Contact, error) {
+	reply, err := address.New(addr, 0).FindValueRPC(ctx, c.n.Conns, &proto.FindRequest{
+		Sender: c.sender(),
+		Target: key[:],
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if reply.Record != nil {
+		if r, err := dht.DeserializeRecord(reply.Record); err == nil {
+			return r, nil, nil
+		}
+	}
+	return nil, contacts(reply.Contacts), nil
+}
+
+func (c *dhtClient) Store(ctx context.Context, addr string, r *dht.Record) error {
+	rec, err := r.Serialize()
+	if err != nil {
+		return err
+	}
+	_, err = address.New(addr, 0).StoreRPC(ctx, c.n.Conns, &proto.StoreRequest{
+		Sender: c.sender(),
+		Record: rec,
+	})
+	return err
+}
+
+func contacts(in []*proto.Contact) []dht.Contact {
+	var cs []dht.Contact
+	for _, pc := range in {
+		if c, err := dht.DeserializeContact(pc); err == nil {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// Fingerprint returns the node's identity.
+func (n *Node) Fingerprint() string {
+	return n.DHT.Self().String()
+}
+
+// dhtSender adds the sender of a DHT query to the routing table. When
+// the connection is authenticated the claimed ID must be the caller's.
+func (n *Node) dhtSender(ctx context.Context, in *proto.Contact) {
+	c, err := dht.DeserializeContact(in)
+	if err != nil {
+		return
+	}
+	tlsKey, err := n.peerKey(ctx)
+	if err != nil {
+		return
+	}
+	if tlsKey != nil {
+		if kid, err := dht.KeyID(tlsKey); err != nil || kid != c.ID {
+			n.misbehaving(ctx, scoreBadHandshake, "dht sender id does not match connection identity")
+			return
+		}
+	}
+	n.DHT.Update(c)
+}
+
+func targetID(b []byte) (dht.NodeID, error) {
+	var nid dht.NodeID
+	if len(b) != dht.IDBytes {
+		return nid, errors.New("invalid target id")
+	}
+	copy(nid[:], b)
+	return nid, nil
+}
+
+func serializeContacts(cs []dht.Contact) []*proto.Contact {
+	var out []*proto.Contact
+	for _, c := range cs {
+		out = append(out, c.Serialize())
+	}
+	return out
+}
+
+func (n *Node) FindNode(ctx context.Context, in *proto.FindRequest) (*proto.FindNodeReply, error) {
+	target, err := targetID(in.Target)
+	if err != nil {
+		n.misbehaving(ctx, scoreMalformed, "invalid find node target")
+		return &proto.FindNodeReply{}, err
+	}
+	n.dhtSender(ctx, in.Sender)
+	return &proto.FindNodeReply{Contacts: serializeContacts(n.DHT.HandleFindNode(target))}, nil
+}
+
+func (n *Node) FindValue(ctx context.Context, in *proto.FindRequest) (*proto.FindValueReply, error) {
+	key, err := targetID(in.Target)
+	if err != nil {
+		n.misbehaving(ctx, scoreMalformed, "invalid find value target")
+		return &proto.FindValueReply{}, err
+	}
+	n.dhtSender(ctx, in.Sender)
+	r, cs := n.DHT.HandleFindValue(key)
+	if r != nil {
+		rec, err := r.Serialize()
+		if err != nil {
+			return &proto.FindValueReply{}, err
+		}
+		return &proto.FindValueReply{Record: rec}, nil
+	}
+	return &proto.FindValueReply{Contacts: serializeContacts(cs)}, nil
+}
+
+func (n *Node) Store(ctx context.Context, in *proto.StoreRequest) (*proto.Empty, error) {
+	if in.Record == nil {
+		n.misbehaving(ctx, scoreMalformed, "store without record")
+		return &proto.Empty{}, errors.New("store without record")
+	}
+	r, err := dht.DeserializeRecord(in.Record)
+	if err != nil {
+		n.misbehaving(ctx, scoreMalformed, "undecodable record")
+		return &proto.Empty{}, err
+	}
+	if err := n.DHT.HandleStore(r); err == dht.ErrNotStored {
+		return &proto.Empty{}, err
+	} else if err != nil {
+		n.misbehaving(ctx, scoreBadSignature, "invalid record")
+		return &proto.Empty{}, err
+	}
+	n.dhtSender(ctx, in.Sender)
+	return &proto.Empty{}, nil
+}
+
+// publish announces the node's current address under its identity.
+func (n *Node) publish() {
+	r, err := dht.NewRecord(n.Id.PrivateKey, []string{n.Addr})
+	if err != nil {
+		utils.Err.Printf("%v received error when signing dht record", utils.FmtAddr(n.Addr))
+		return
+	}
+	stores, err := n.DHT.Publish(n.ctx, r)
+	if err != nil {
+		utils.Err.Printf("%v could not publish dht record: %v", utils.FmtAddr(n.Addr), err)
+		return
+	}
+	utils.Debug.Printf("%v published its address to %v nodes", utils.FmtAddr(n.Addr), stores)
+}
+
+// peerByID returns the peer whose key has the fingerprint fp, or nil.
+func (n *Node) peerByID(fp string) *peer.Peer {
+	for _, p := range n.PeerDb.List() {
+		if p.ID() == fp {
+			return p
+		}
+	}
+	return nil
+}
+
+// Resolve returns a reachable address of the node identified by the
+// key fingerprint fp, connecting to it if it is not already a peer.
+func (n *Node) Resolve(fp string) (string, error) {
+	if p := n.peerByID(fp); p != nil {
+		return p.Addr.Addr, nil
+	}
+	key, err := dht.ParseID(fp)
+	if err != nil {
+		return "", err
+	}
+	r, err := n.DHT.Resolve(n.ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return n.connectToRecord(r, "")
+}
+
+// connectToMember connects to a new group member at the address it was
+// announced with, or at the one its identity resolves to if that fails
+// or turns out to be held by another key.
+func (n *Node) connectToMember(m group.Member) {
+	if m.Addr != "" && n.handshake(m.Addr) == nil {
+		if p := n.PeerDb.Get(m.Addr); p != nil && p.ID() == m.ID {
+			return
+		}
+	}
+	if _, err := n.Resolve(m.ID); err != nil {
+		utils.Debug.Printf("%v could not reach member %v",
+			utils.FmtAddr(n.Addr), utils.FmtAddr(m.Addr))
+	}
+}
+
+// connectToRecord connects to the first address other than skip listed
+// in r that is reachable and held by the record's key.
+func (n *Node) connectToRecord(r *dht.Record, skip string) (string, error) {
+	for _, addr := range r.Addrs {
+		if addr == n.Addr || addr == skip {
+			continue
+		}
+		if !n.PeerDb.In(addr) && n.handshake(addr) != nil {
+			continue
+		}
+		if p := n.PeerDb.Get(addr); p != nil && p.PublicKey.Equal(r.PublicKey) {
+			return addr, nil
+		}
+	}
+	return "", errors.New("identity unreachable")
+}
+
+// relocate looks up the current address of a group member that could
+// not be reached at its last known one and, if it moved, updates the
+// group and returns the member at its new address.
+func (n *Node) relocate(p *peer.Peer) (*peer.Peer, error) {
+	key, err := dht.KeyID(p.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := n.DHT.Find(n.ctx, key); err != nil {
+		return nil, err
+	}
+	// Find keeps whichever of the fetched and local records is newer.
+	r := n.DHT.Local(key)
+	if r == nil {
+		return nil, errors.New("record not found")
+	}
+	addr, err := n.connectToRecord(r, p.Addr.Addr)
+	if err != nil {
+		return nil, err
+	}
+	q := n.PeerDb.Get(addr)
+	if q == nil {
+		return nil, errors.New("peer not found")
+	}
+	n.Group.Update(q)
+	utils.Debug.Printf("%v found member %v at %v",
+		utils.FmtAddr(n.Addr), utils.FmtAddr(p.Addr.Addr), utils.FmtAddr(addr))
+	return q, nil
+}
+
+// sendToMember calls send with p's address, and once more with its
+// new address if p has moved since.
+func (n *Node) sendToMember(p *peer.Peer, send func(*address.Address) error) error {
+	err := send(p.Addr)
+	if err == nil {
+		return nil
+	}
+	q, rerr := n.relocate(p)
+	if rerr != nil {
+		return err
+	}
+	return send(q.Addr)
+}
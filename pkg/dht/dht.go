@@ -0,0 +1,484 @@
+package dht
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"golang.org/x/net/context"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	IDBytes = 32
+	IDBits  = IDBytes * 8
+
+	DefaultK     = 8
+	DefaultAlpha = 3
+	// RecordTTL is how long a stored record is served after it was
+	// last published.
+	RecordTTL = 24 * time.Hour
+	// DefaultRecordLimit is how many records a node stores for others.
+	DefaultRecordLimit = 4096
+)
+
+// ErrNotStored is returned for a valid record the node declines to
+// store, because other nodes it knows of are closer to the record's
+// identity or because its store is full of closer records.
+var ErrNotStored = errors.New("record not stored here")
+
+// NodeID identifies a node by the SHA-256 fingerprint of its public
+// key.
+type NodeID [IDBytes]byte
+
+func (id NodeID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func distance(a, b NodeID) NodeID {
+	var d NodeID
+	for i := range d {
+		d[i] = a[i] ^ b[i]
+	}
+	return d
+}
+
+// closer reports whether a is closer to target than b.
+func closer(target, a, b NodeID) bool {
+	da, db := distance(a, target), distance(b, target)
+	return bytes.Compare(da[:], db[:]) < 0
+}
+
+// bucketIndex returns the number of leading bits a and b share, which
+// selects the bucket b belongs in for a node with ID a.
+func bucketIndex(a, b NodeID) int {
+	d := distance(a, b)
+	for i, x := range d {
+		if x != 0 {
+			return i*8 + bits.LeadingZeros8(x)
+		}
+	}
+	return IDBits - 1
+}
+
+// Client sends the DHT's queries to other nodes, giving up when ctx is
+// done.
+type Client interface {
+	FindNode(ctx context.Context, addr string, target NodeID) ([]Contact, error)
+	FindValue(ctx context.Context, addr string, key NodeID) (*Record, []Contact, error)
+	Store(ctx context.Context, addr string, r *Record) error
+}
+
+type stored struct {
+	record  *Record
+	expires time.Time
+}
+
+// DHT is a Kademlia routing table and record store. Contacts live in
+// one bucket of up to K entries per shared prefix length; a full bucket
+// keeps its oldest contacts, which are the most likely to stay online.
+// Lookups query Alpha contacts at a time, converging on the K nodes
+// closest to the target.
+type DHT struct {
+	self   NodeID
+	client Client
+	k      int
+	alpha  int
+
+	buckets     [IDBits][]Contact
+	records     map[NodeID]*stored
+	recordLimit int
+	sync.Mutex
+}
+
+func New(self NodeID, client Client, k, alpha int) *DHT {
+	if k <= 0 {
+		k = DefaultK
+	}
+	if alpha <= 0 {
+		alpha = DefaultAlpha
+	}
+	return &DHT{
+		self:        self,
+		client:      client,
+		k:           k,
+		alpha:       alpha,
+		records:     make(map[NodeID]*stored),
+		recordLimit: DefaultRecordLimit,
+	}
+}
+
+// SetRecordLimit caps the records the DHT stores. Once it is full, a
+// record replaces the one furthest from the DHT's own ID if it is
+// closer. A limit of 0 leaves the store unbounded.
+func (d *DHT) SetRecordLimit(limit int) {
+	d.Lock()
+	defer d.Unlock()
+	d.recordLimit = limit
+}
+
+func (d *DHT) Self() NodeID {
+	return d.self
+}
+
+// Update records that c was seen. A contact already known under the
+// same ID moves to the back of its bucket and takes c's address.
+func (d *DHT) Update(c Contact) {
+	if c.ID == d.self {
+		return
+	}
+	d.Lock()
+	defer d.Unlock()
+	i := bucketIndex(d.self, c.ID)
+	b := d.buckets[i]
+	for j, old := range b {
+		if old.ID == c.ID {
+			b = append(b[:j], b[j+1:]...)
+			d.buckets[i] = append(b, c)
+			return
+		}
+	}
+	if len(b) < d.k {
+		d.buckets[i] = append(b, c)
+	}
+}
+
+// Remove forgets the contact with the given ID.
+func (d *DHT) Remove(nid NodeID) {
+	d.Lock()
+	defer d.Unlock()
+	i := bucketIndex(d.self, nid)
+	b := d.buckets[i]
+	for j, c := range b {
+		if c.ID == nid {
+			d.buckets[i] = append(b[:j], b[j+1:]...)
+			return
+		}
+	}
+}
+
+// Closest returns up to n known contacts ordered by distance to
+// target.
+func (d *DHT) Closest(target NodeID, n int) []Contact {
+	d.Lock()
+	var all []Contact
+	for _, b := range d.buckets {
+		all = append(all, b...)
+	}
+	d.Unlock()
+	sort.Slice(all, func(i, j int) bool {
+		return closer(target, all[i].ID, all[j].ID)
+	})
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
+
+// Len returns the number of contacts in the routing table.
+func (d *DHT) Len() int {
+	d.Lock()
+	defer d.Unlock()
+	n := 0
+	for _, b := range d.buckets {
+		n += len(b)
+	}
+	return n
+}
+
+// Local returns the unexpired record stored for key, if any.
+func (d *DHT) Local(key NodeID) *Record {
+	d.Lock()
+	defer d.Unlock()
+	s, ok := d.records[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(s.expires) {
+		delete(d.records, key)
+		return nil
+	}
+	return s.record
+}
+
+// HandleFindNode answers a FindNode query.
+func (d *DHT) HandleFindNode(target NodeID) []Contact {
+	return d.Closest(target, d.k)
+}
+
+// HandleFindValue answers a FindValue query with the stored record
+// or, failing that, the closest known contacts.
+func (d *DHT) HandleFindValue(key NodeID) (*Record, []Contact) {
+	if r := d.Local(key); r != nil {
+		return r, nil
+	}
+	return nil, d.Closest(key, d.k)
+}
+
+// HandleStore verifies r and stores it unless a newer record for the
+// same identity is already held. A record for a new identity is only
+// stored if the DHT is among the K closest nodes it knows of to that
+// identity, and there is room for it; otherwise it returns
+// ErrNotStored.
+func (d *DHT) HandleStore(r *Record) error {
+	if err := r.Verify(); err != nil {
+		return err
+	}
+	key, err := r.ID()
+	if err != nil {
+		return err
+	}
+	d.Lock()
+	defer d.Unlock()
+	now := time.Now()
+	if s, ok := d.records[key]; ok {
+		if s.record.Timestamp < r.Timestamp {
+			d.records[key] = &stored{record: r, expires: now.Add(RecordTTL)}
+		}
+		return nil
+	}
+	if !d.responsible(key) {
+		return ErrNotStored
+	}
+	if d.recordLimit > 0 && len(d.records) >= d.recordLimit {
+		// Expired records are only swept once the store is full.
+		for k, s := range d.records {
+			if now.After(s.expires) {
+				delete(d.records, k)
+			}
+		}
+	}
+	if d.recordLimit > 0 && len(d.records) >= d.recordLimit {
+		far := d.furthest()
+		if !closer(d.self, key, far) {
+			return ErrNotStored
+		}
+		delete(d.records, far)
+	}
+	d.records[key] = &stored{record: r, expires: now.Add(RecordTTL)}
+	return nil
+}
+
+// responsible reports whether fewer than K known contacts are closer
+// to key than the DHT itself. It is called with d locked.
+func (d *DHT) responsible(key NodeID) bool {
+	n := 0
+	for _, b := range d.buckets {
+		for _, c := range b {
+			if closer(key, c.ID, d.self) {
+				if n++; n >= d.k {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// furthest returns the key of the stored record furthest from the
+// DHT's own ID. It is called with d locked.
+func (d *DHT) furthest() NodeID {
+	var far NodeID
+	first := true
+	for k := range d.records {
+		if first || closer(d.self, far, k) {
+			far, first = k, false
+		}
+	}
+	return far
+}
+
+// Records returns the number of records stored.
+func (d *DHT) Records() int {
+	d.Lock()
+	defer d.Unlock()
+	return len(d.records)
+}
+
+// Lookup returns the K nodes closest to target that answered, or the
+// closest found so far once ctx is done.
+func (d *DHT) Lookup(ctx context.Context, target NodeID) []Contact {
+	contacts, _ := d.lookup(ctx, target, false)
+	return contacts
+}
+
+// Publish stores r on the K nodes closest to its identity, and locally
+// if this node is among them. It returns how many other nodes accepted
+// it.
+func (d *DHT) Publish(ctx context.Context, r *Record) (int, error) {
+	if err := d.HandleStore(r); err != nil && err != ErrNotStored {
+		return 0, err
+	}
+	key, err := r.ID()
+	if err != nil {
+		return 0, err
+	}
+	stores := 0
+	for _, c := range d.Lookup(ctx, key) {
+		if d.client.Store(ctx, c.Addr, r) == nil {
+			stores++
+		}
+	}
+	return stores, nil
+}
+
+// Resolve returns the newest record for key, from the local store if
+// it is held here and otherwise from the network.
+func (d *DHT) Resolve(ctx context.Context, key NodeID) (*Record, error) {
+	if r := d.Local(key); r != nil {
+		return r, nil
+	}
+	return d.Find(ctx, key)
+}
+
+// Find looks key up on the network, skipping the local store, and
+// caches the result.
+func (d *DHT) Find(ctx context.Context, key NodeID) (*Record, error) {
+	_, r := d.lookup(ctx, key, true)
+	if r == nil {
+		return nil, errors.New("record not found")
+	}
+	_ = d.HandleStore(r)
+	return r, nil
+}
+
+type result struct {
+	from     Contact
+	record   *Record
+	contacts []Contact
+	err      error
+}
+
+// lookup runs an iterative lookup of target. When value is set it
+// stops at the first node that returns a valid record for target.
+func (d *DHT) lookup(ctx context.Context, target NodeID, value bool) ([]Contact, *Record) {
+	s := newShortlist(target, d.k)
+	s.add(d.self, d.Closest(target, d.k)...)
+	for {
+		batch := s.next(d.alpha)
+		if len(batch) == 0 || ctx.Err() != nil {
+			return s.closest(), nil
+		}
+		results := make(chan result, len(batch))
+		for _, c := range batch {
+			go func(c Contact) {
+				r := result{from: c}
+				if value {
+					r.record, r.contacts, r.err = d.client.FindValue(ctx, c.Addr, target)
+				} else {
+					r.contacts, r.err = d.client.FindNode(ctx, c.Addr, target)
+				}
+				results <- r
+			}(c)
+		}
+		var found *Record
+		for range batch {
+			r := <-results
+			if r.err != nil && ctx.Err() != nil {
+				// The node did not fail, the lookup was cancelled.
+				continue
+			}
+			if r.err != nil {
+				s.fail(r.from.ID)
+				d.Remove(r.from.ID)
+				continue
+			}
+			d.Update(r.from)
+			if r.record != nil && validFor(r.record, target) {
+				if found == nil || r.record.Timestamp > found.Timestamp {
+					found = r.record
+				}
+			}
+			if len(r.contacts) > d.k {
+				r.contacts = r.contacts[:d.k]
+			}
+			s.add(d.self, r.contacts...)
+		}
+		if found != nil {
+			return s.closest(), found
+		}
+	}
+}
+
+func validFor(r *Record, key NodeID) bool {
+	rid, err := r.ID()
+	return err == nil && rid == key && r.Verify() == nil
+}
+
+type candidate struct {
+	c       Contact
+	queried bool
+	failed  bool
+}
+
+// shortlist holds the contacts a lookup has heard of, ordered by
+// distance to the target.
+type shortlist struct {
+	target NodeID
+	k      int
+	seen   map[NodeID]*candidate
+	order  []*candidate
+}
+
+func newShortlist(target NodeID, k int) *shortlist {
+	return &shortlist{target: target, k: k, seen: make(map[NodeID]*candidate)}
+}
+
+func (s *shortlist) add(self NodeID, cs ...Contact) {
+	for _, c := range cs {
+		if c.ID == self {
+			continue
+		}
+		if _, ok := s.seen[c.ID]; ok {
+			continue
+		}
+		cand := &candidate{c: c}
+		s.seen[c.ID] = cand
+		s.order = append(s.order, cand)
+	}
+	sort.Slice(s.order, func(i, j int) bool {
+		return closer(s.target, s.order[i].c.ID, s.order[j].c.ID)
+	})
+}
+
+func (s *shortlist) fail(nid NodeID) {
+	if cand, ok := s.seen[nid]; ok {
+		cand.failed = true
+	}
+}
+
+// next marks and returns up to n unqueried contacts among the K
+// closest that have not failed.
+func (s *shortlist) next(n int) []Contact {
+	var batch []Contact
+	live := 0
+	for _, cand := range s.order {
+		if cand.failed {
+			continue
+		}
+		if live++; live > s.k || len(batch) == n {
+			break
+		}
+		if !cand.queried {
+			cand.queried = true
+			batch = append(batch, cand.c)
+		}
+	}
+	return batch
+}
+
+// closest returns the K closest contacts that answered.
+func (s *shortlist) closest() []Contact {
+	var cs []Contact
+	for _, cand := range s.order {
+		if cand.queried && !cand.failed {
+			cs = append(cs, cand.c)
+			if len(cs) == s.k {
+				break
+			}
+		}
+	}
+	return cs
+}
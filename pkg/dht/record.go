@@ -0,0 +1,156 @@
+package dht
+
+import (
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"finalbruh/pkg/id"
+	"finalbruh/pkg/netaddr"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/utils"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxRecordAddrs bounds how many addresses a record may list.
+	MaxRecordAddrs = 8
+	// maxClockSkew is how far in the future a record's timestamp may
+	// be before it is refused.
+	maxClockSkew = 10 * time.Minute
+)
+
+// Record maps the identity of PublicKey to the addresses its owner can
+// currently be reached at. It is signed by the owner, so any node may
+// store and serve it without being able to forge it.
+type Record struct {
+	PublicKey *rsa.PublicKey
+	Addrs     []string
+	Timestamp int64
+	Sig       string
+}
+
+func recordMsg(fp string, addrs []string, ts int64) string {
+	return fmt.Sprintf("dht-record:%v:%v:%d", fp, strings.Join(addrs, ","), ts)
+}
+
+// NewRecord returns a record for sk's public key listing addrs, signed
+// with sk.
+func NewRecord(sk *rsa.PrivateKey, addrs []string) (*Record, error) {
+	fp, err := id.Fingerprint(&sk.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	r := &Record{
+		PublicKey: &sk.PublicKey,
+		Addrs:     addrs,
+		Timestamp: time.Now().UnixNano(),
+	}
+	r.Sig, err = utils.Sign(sk, recordMsg(fp, addrs, r.Timestamp))
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ID returns the identity the record describes.
+func (r *Record) ID() (NodeID, error) {
+	return KeyID(r.PublicKey)
+}
+
+// Verify checks that the record is well formed and signed by the key
+// it describes.
+func (r *Record) Verify() error {
+	if r.PublicKey == nil {
+		return errors.New("record without key")
+	}
+	if len(r.Addrs) == 0 || len(r.Addrs) > MaxRecordAddrs {
+		return errors.New("record has too few or too many addresses")
+	}
+	for _, a := range r.Addrs {
+		if hp, err := netaddr.Parse(a); err != nil || !hp.Dialable() {
+			return errors.New("record lists an invalid address")
+		}
+	}
+	if time.Unix(0, r.Timestamp).After(time.Now().Add(maxClockSkew)) {
+		return errors.New("record timestamp is in the future")
+	}
+	fp, err := id.Fingerprint(r.PublicKey)
+	if err != nil {
+		return err
+	}
+	if !utils.Verify(r.PublicKey, recordMsg(fp, r.Addrs, r.Timestamp), r.Sig) {
+		return errors.New("invalid record signature")
+	}
+	return nil
+}
+
+func (r *Record) Serialize() (*proto.DhtRecord, error) {
+	pk, err := utils.EncodePublicKey(r.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.DhtRecord{
+		SerPk:     pk,
+		Addrs:     r.Addrs,
+		Timestamp: r.Timestamp,
+		Sig:       r.Sig,
+	}, nil
+}
+
+// DeserializeRecord decodes a record without verifying it.
+func DeserializeRecord(r *proto.DhtRecord) (*Record, error) {
+	pk, err := utils.DecodePublicKey(r.SerPk)
+	if err != nil {
+		return nil, err
+	}
+	return &Record{
+		PublicKey: pk,
+		Addrs:     r.Addrs,
+		Timestamp: r.Timestamp,
+		Sig:       r.Sig,
+	}, nil
+}
+
+// Contact is a node in the routing table.
+type Contact struct {
+	ID   NodeID
+	Addr string
+}
+
+func (c Contact) Serialize() *proto.Contact {
+	return &proto.Contact{Id: c.ID[:], Addr: c.Addr}
+}
+
+func DeserializeContact(c *proto.Contact) (Contact, error) {
+	if c == nil || len(c.Id) != IDBytes {
+		return Contact{}, errors.New("invalid contact id")
+	}
+	if hp, err := netaddr.Parse(c.Addr); err != nil || !hp.Dialable() {
+		return Contact{}, errors.New("invalid contact address")
+	}
+	var nid NodeID
+	copy(nid[:], c.Id)
+	return Contact{ID: nid, Addr: c.Addr}, nil
+}
+
+// ParseID decodes the hex fingerprint returned by id.Fingerprint.
+func ParseID(fp string) (NodeID, error) {
+	var nid NodeID
+	b, err := hex.DecodeString(fp)
+	if err != nil || len(b) != IDBytes {
+		return nid, errors.New("invalid identity fingerprint")
+	}
+	copy(nid[:], b)
+	return nid, nil
+}
+
+// KeyID returns the identity of pk.
+func KeyID(pk *rsa.PublicKey) (NodeID, error) {
+	fp, err := id.Fingerprint(pk)
+	if err != nil {
+		return NodeID{}, err
+	}
+	return ParseID(fp)
+}
@@ -0,0 +1,215 @@
+package dispatch
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"sync"
+)
+
+// ErrClosed is returned for jobs submitted after Close.
+var ErrClosed = errors.New("dispatcher closed")
+
+// Stats describes the load of a Dispatcher.
+type Stats struct {
+	// Workers is the number of workers running and Busy the number of
+	// keys being served.
+	Workers int
+	Busy    int
+	// Queued is the number of jobs waiting, in total and per key.
+	Queued int
+	Queues map[string]int
+	// Done counts the jobs run and Dropped those refused by TrySubmit
+	// because their queue was full or removed by Discard.
+	Done    uint64
+	Dropped uint64
+}
+
+// Dispatcher runs jobs on a bounded pool of workers. Jobs wait in a
+// queue per key, usually the peer they are sent to, and run in order
+// one at a time for each key. Workers take turns between the keys with
+// waiting jobs, so one slow or busy key cannot hold back the others.
+type Dispatcher struct {
+	maxWorkers int
+	queueLimit int
+
+	queues  map[string][]func()
+	ready   []string
+	busy    map[string]bool
+	workers int
+	done    uint64
+	dropped uint64
+	closed  bool
+	// freed is closed and replaced whenever a job leaves a queue, to
+	// wake submitters waiting for room.
+	freed chan struct{}
+	wg    sync.WaitGroup
+	sync.Mutex
+}
+
+// New returns a dispatcher running at most workers jobs at once, with
+// at most queueLimit jobs waiting per key. Workers are started as jobs
+// arrive and stop when there is nothing left to do.
+func New(workers, queueLimit int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueLimit < 1 {
+		queueLimit = 1
+	}
+	return &Dispatcher{
+		maxWorkers: workers,
+		queueLimit: queueLimit,
+		queues:     make(map[string][]func()),
+		busy:       make(map[string]bool),
+		freed:      make(chan struct{}),
+	}
+}
+
+// Submit queues f to run for key, waiting while key's queue is full.
+// It fails if ctx is done first or the dispatcher is closed.
+func (d *Dispatcher) Submit(ctx context.Context, key string, f func()) error {
+	for {
+		d.Lock()
+		if d.closed {
+			d.Unlock()
+			return ErrClosed
+		}
+		if len(d.queues[key]) < d.queueLimit {
+			d.push(key, f)
+			d.Unlock()
+			return nil
+		}
+		freed := d.freed
+		d.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TrySubmit queues f to run for key unless key's queue is full or the
+// dispatcher is closed, and reports whether it did.
+func (d *Dispatcher) TrySubmit(key string, f func()) bool {
+	d.Lock()
+	defer d.Unlock()
+	if d.closed {
+		return false
+	}
+	if len(d.queues[key]) >= d.queueLimit {
+		d.dropped++
+		return false
+	}
+	d.push(key, f)
+	return true
+}
+
+// push queues f and starts a worker if one is allowed. The caller must
+// hold the lock.
+func (d *Dispatcher) push(key string, f func()) {
+	if len(d.queues[key]) == 0 && !d.busy[key] {
+		d.ready = append(d.ready, key)
+	}
+	d.queues[key] = append(d.queues[key], f)
+	// Every key being served has a worker; start another for the keys
+	// waiting without one.
+	if d.workers < d.maxWorkers && d.workers < len(d.busy)+len(d.ready) {
+		d.workers++
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+// work runs jobs until none are ready.
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	d.Lock()
+	for len(d.ready) > 0 {
+		key := d.ready[0]
+		d.ready = d.ready[1:]
+		q := d.queues[key]
+		f := q[0]
+		if len(q) == 1 {
+			delete(d.queues, key)
+		} else {
+			d.queues[key] = q[1:]
+		}
+		d.busy[key] = true
+		close(d.freed)
+		d.freed = make(chan struct{})
+		d.Unlock()
+
+		f()
+
+		d.Lock()
+		delete(d.busy, key)
+		d.done++
+		// The key goes to the back of the line so that the others get
+		// their turn first.
+		if len(d.queues[key]) > 0 {
+			d.ready = append(d.ready, key)
+		}
+	}
+	d.workers--
+	d.Unlock()
+}
+
+// Discard drops the jobs waiting for key, counting them as dropped,
+// and returns how many there were. A job already running for key is
+// not affected.
+func (d *Dispatcher) Discard(key string) int {
+	d.Lock()
+	defer d.Unlock()
+	k := len(d.queues[key])
+	if k == 0 {
+		return 0
+	}
+	delete(d.queues, key)
+	for i, r := range d.ready {
+		if r == key {
+			d.ready = append(d.ready[:i], d.ready[i+1:]...)
+			break
+		}
+	}
+	d.dropped += uint64(k)
+	close(d.freed)
+	d.freed = make(chan struct{})
+	return k
+}
+
+// Close refuses further jobs. Jobs already queued still run.
+func (d *Dispatcher) Close() {
+	d.Lock()
+	defer d.Unlock()
+	if !d.closed {
+		d.closed = true
+		// Wake submitters waiting for room so they see the closure.
+		close(d.freed)
+		d.freed = make(chan struct{})
+	}
+}
+
+// Wait blocks until no worker is running. After Close that means every
+// queued job has run.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// Stats returns the dispatcher's current load.
+func (d *Dispatcher) Stats() Stats {
+	d.Lock()
+	defer d.Unlock()
+	s := Stats{
+		Workers: d.workers,
+		Busy:    len(d.busy),
+		Queues:  make(map[string]int, len(d.queues)),
+		Done:    d.done,
+		Dropped: d.dropped,
+	}
+	for key, q := range d.queues {
+		s.Queues[key] = len(q)
+		s.Queued += len(q)
+	}
+	return s
+}
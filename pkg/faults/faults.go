@@ -0,0 +1,223 @@
+package faults
+
+import (
+	"finalbruh/pkg/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// fromKey is the metadata key carrying the sender's address so that
+// the receiving side can enforce partitions too.
+const fromKey = "x-fault-from"
+
+// Fault describes what happens to RPCs on a link.
+type Fault struct {
+	// Drop is the probability an RPC fails with Unavailable without
+	// being delivered.
+	Drop float64
+	// Delay is added before every RPC is delivered, plus a random
+	// extra delay of up to Jitter.
+	Delay  time.Duration
+	Jitter time.Duration
+	// Duplicate is the probability an RPC is delivered twice.
+	Duplicate float64
+	// Reorder is the probability an RPC with an empty reply is
+	// acknowledged immediately and delivered up to ReorderWindow
+	// later, letting subsequent RPCs overtake it.
+	Reorder       float64
+	ReorderWindow time.Duration
+}
+
+type link struct {
+	from, to string
+}
+
+// Network injects faults into the RPCs of every node configured with
+// it. Nodes are identified by their advertised address.
+type Network struct {
+	all   Fault
+	links map[link]Fault
+	sets  map[string]map[string]bool
+	cuts  map[link]bool
+	rng   *rand.Rand
+	sync.Mutex
+}
+
+func NewNetwork(seed int64) *Network {
+	return &Network{
+		links: make(map[link]Fault),
+		sets:  make(map[string]map[string]bool),
+		cuts:  make(map[link]bool),
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Set applies f to every link without a more specific fault.
+func (nw *Network) Set(f Fault) {
+	nw.Lock()
+	defer nw.Unlock()
+	nw.all = f
+}
+
+// SetLink applies f to RPCs sent from one address to another.
+func (nw *Network) SetLink(from, to string, f Fault) {
+	nw.Lock()
+	defer nw.Unlock()
+	nw.links[link{from, to}] = f
+}
+
+// Name defines a named set of node addresses for use in Partition.
+func (nw *Network) Name(set string, addrs ...string) {
+	nw.Lock()
+	defer nw.Unlock()
+	members := make(map[string]bool)
+	for _, a := range addrs {
+		members[a] = true
+	}
+	nw.sets[set] = members
+}
+
+// Partition cuts every link between the named sets a and b in both
+// directions.
+func (nw *Network) Partition(a, b string) {
+	nw.Lock()
+	defer nw.Unlock()
+	nw.cuts[link{a, b}] = true
+	nw.cuts[link{b, a}] = true
+}
+
+// Heal removes every partition and fault.
+func (nw *Network) Heal() {
+	nw.Lock()
+	defer nw.Unlock()
+	nw.all = Fault{}
+	nw.links = make(map[link]Fault)
+	nw.cuts = make(map[link]bool)
+}
+
+func (nw *Network) partitioned(from, to string) bool {
+	nw.Lock()
+	defer nw.Unlock()
+	for c := range nw.cuts {
+		if nw.sets[c.from][from] && nw.sets[c.to][to] {
+			return true
+		}
+	}
+	return false
+}
+
+func (nw *Network) fault(from, to string) Fault {
+	nw.Lock()
+	defer nw.Unlock()
+	if f, ok := nw.links[link{from, to}]; ok {
+		return f
+	}
+	return nw.all
+}
+
+func (nw *Network) chance(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	nw.Lock()
+	defer nw.Unlock()
+	return nw.rng.Float64() < p
+}
+
+func (nw *Network) upTo(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	nw.Lock()
+	defer nw.Unlock()
+	return time.Duration(nw.rng.Int63n(int64(d)))
+}
+
+// ClientInterceptor applies the network's faults to RPCs sent by the
+// node whose address self returns.
+func (nw *Network) ClientInterceptor(self func() string) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		from, to := self(), cc.Target()
+		if nw.partitioned(from, to) {
+			return status.Error(codes.Unavailable, "fault injection: partitioned")
+		}
+		f := nw.fault(from, to)
+		if nw.chance(f.Drop) {
+			return status.Error(codes.Unavailable, "fault injection: dropped")
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, fromKey, from)
+		deliver := func(ctx context.Context) error {
+			if err := sleep(ctx, f.Delay+nw.upTo(f.Jitter)); err != nil {
+				return err
+			}
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if nw.chance(f.Duplicate) {
+				_ = invoker(ctx, method, req, reply, cc, opts...)
+			}
+			return err
+		}
+		if _, empty := reply.(*proto.Empty); empty && nw.chance(f.Reorder) {
+			held := nw.upTo(f.ReorderWindow)
+			timeout := time.Minute
+			if deadline, ok := ctx.Deadline(); ok {
+				timeout = time.Until(deadline)
+			}
+			md, _ := metadata.FromOutgoingContext(ctx)
+			go func() {
+				time.Sleep(held)
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				_ = deliver(metadata.NewOutgoingContext(ctx, md))
+			}()
+			return nil
+		}
+		return deliver(ctx)
+	}
+}
+
+// ServerInterceptor refuses RPCs that reach the node whose address
+// self returns across a partition.
+func (nw *Network) ServerInterceptor(self func() string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			for _, from := range md.Get(fromKey) {
+				if nw.partitioned(from, self()) {
+					return nil, status.Error(codes.Unavailable, "fault injection: partitioned")
+				}
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
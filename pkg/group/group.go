@@ -2,18 +2,42 @@ package group
 
 import (
 	"crypto/cipher"
+	"errors"
 	"finalbruh/pkg/peer"
 	"finalbruh/pkg/utils"
+	"sync"
 )
 
+// ErrNoGroup is returned when encrypting or decrypting without a group
+// key.
+var ErrNoGroup = errors.New("not in a group")
+
+// Group is the set of members a node shares a symmetric key with. It
+// is safe for concurrent use.
 type Group struct {
-	Members []*peer.Peer
-	Key     string
-	GCM     cipher.AEAD
+	members []*peer.Peer
+	key     string
+	gcm     cipher.AEAD
+
+	// offline holds members the failure detector declared dead. They
+	// stay in the group but are skipped when sending.
+	offline map[string]bool
+
+	mu sync.RWMutex
+}
+
+func New() *Group {
+	return &Group{}
 }
 
-func New() Group {
-	return Group{}
+// Reset empties the group and forgets its key.
+func (g *Group) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.members = nil
+	g.key = ""
+	g.gcm = nil
+	g.offline = nil
 }
 
 func (g *Group) ReplaceKeys(key string) {
@@ -21,54 +45,164 @@ func (g *Group) ReplaceKeys(key string) {
 	if err != nil {
 		utils.Err.Printf("Cannot successfully generate sym key")
 	}
-	g.GCM = gcm
-	g.Key = key
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.gcm = gcm
+	g.key = key
 }
 
-func (g *Group) GenerateNewKeys() {
+// GenerateNewKeys replaces the group key with a fresh one and returns
+// it.
+func (g *Group) GenerateNewKeys() string {
 	key, gcm, err := utils.GenerateSymKey()
 	if err != nil {
 		utils.Err.Printf("Cannot successfully generate sym key")
 	}
-	g.Key = key
-	g.GCM = gcm
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.key = key
+	g.gcm = gcm
+	return key
+}
+
+func (g *Group) Key() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.key
+}
+
+// Encrypt encrypts message under the group key.
+func (g *Group) Encrypt(message string) (string, error) {
+	g.mu.RLock()
+	gcm := g.gcm
+	g.mu.RUnlock()
+	if gcm == nil {
+		return "", ErrNoGroup
+	}
+	return utils.SymEncrypt(gcm, message), nil
 }
 
-func (g *Group) GetMembers() []string {
-	var newSlice []string
-	for _, val := range g.Members {
-		newSlice = append(newSlice, val.Addr.Addr)
+// Decrypt decrypts a message encrypted under the group key.
+func (g *Group) Decrypt(ciphertext string) (string, error) {
+	g.mu.RLock()
+	gcm := g.gcm
+	g.mu.RUnlock()
+	if gcm == nil {
+		return "", ErrNoGroup
+	}
+	return utils.SymDecrypt(gcm, ciphertext)
+}
+
+// Member identifies a group member by the fingerprint of its key,
+// along with the address it was last known at.
+type Member struct {
+	ID   string
+	Addr string
+}
+
+func (g *Group) GetMembers() []Member {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var newSlice []Member
+	for _, val := range g.members {
+		newSlice = append(newSlice, Member{ID: val.ID(), Addr: val.Addr.Addr})
 	}
 	return newSlice
 }
 
+// Members returns the members of the group.
+func (g *Group) Members() []*peer.Peer {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]*peer.Peer(nil), g.members...)
+}
+
+// Get returns the member with the given identity, or nil.
+func (g *Group) Get(id string) *peer.Peer {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, val := range g.members {
+		if val.ID() == id {
+			return val
+		}
+	}
+	return nil
+}
+
+// AddMember adds p to the group. A member with the same identity is
+// replaced, so a member that moved keeps its place under its new
+// address.
 func (g *Group) AddMember(p *peer.Peer) {
-	for _, existingPeer := range g.Members {
-		if existingPeer.Addr.Addr == p.Addr.Addr {
-			// Peer is already in the group
-			return
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.replace(p) {
+		g.members = append(g.members, p)
+	}
+}
+
+// Update replaces the member with p's identity by p, reporting whether
+// there was one.
+func (g *Group) Update(p *peer.Peer) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.replace(p)
+}
+
+func (g *Group) replace(p *peer.Peer) bool {
+	id := p.ID()
+	for i, existingPeer := range g.members {
+		if existingPeer.ID() == id {
+			g.members[i] = p
+			return true
 		}
 	}
-	// Peer is not in the group, so append it
-	g.Members = append(g.Members, p)
+	return false
 }
 
 func (g *Group) KickMember(p *peer.Peer) {
+	g.KickMyMember(p.ID())
+}
+
+func (g *Group) KickMyMember(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	var newSlice []*peer.Peer
-	for _, val := range g.Members {
-		if val.Addr.Addr != p.Addr.Addr {
+	for _, val := range g.members {
+		if val.ID() != id {
 			newSlice = append(newSlice, val)
 		}
 	}
-	g.Members = newSlice
+	g.members = newSlice
 }
 
-func (g *Group) KickMyMember(addr string) {
-	var newSlice []*peer.Peer
-	for _, val := range g.Members {
-		if val.Addr.Addr != addr {
-			newSlice = append(newSlice, val)
+func (g *Group) SetOffline(addr string, offline bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.offline == nil {
+		g.offline = make(map[string]bool)
+	}
+	if offline {
+		g.offline[addr] = true
+	} else {
+		delete(g.offline, addr)
+	}
+}
+
+func (g *Group) IsOffline(addr string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.offline[addr]
+}
+
+// Online returns the members that are not known to be offline.
+func (g *Group) Online() []*peer.Peer {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var online []*peer.Peer
+	for _, val := range g.members {
+		if !g.offline[val.Addr.Addr] {
+			online = append(online, val)
 		}
 	}
-	g.Members = newSlice
+	return online
 }
@@ -0,0 +1,493 @@
+package pkg
+
+import (
+	"crypto/rsa"
+	"errors"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/address/addressdb"
+	"finalbruh/pkg/dht"
+	"finalbruh/pkg/id"
+	"finalbruh/pkg/netaddr"
+	"finalbruh/pkg/peer"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/utils"
+	"fmt"
+	"golang.org/x/net/context"
+	"sync"
+	"time"
+)
+
+const nonceSize = 32
+
+// HandshakeState is how far the Version handshake with an address has
+// got. The initiator goes from HandshakeInitiated to established or
+// failed; the receiver of a Version waits in HandshakeAwaitingAck for
+// the initiator's VerAck.
+type HandshakeState int
+
+const (
+	HandshakeNone HandshakeState = iota
+	HandshakeInitiated
+	HandshakeAwaitingAck
+	HandshakeEstablished
+	HandshakeFailed
+)
+
+func (s HandshakeState) String() string {
+	switch s {
+	case HandshakeInitiated:
+		return "initiated"
+	case HandshakeAwaitingAck:
+		return "awaiting-ack"
+	case HandshakeEstablished:
+		return "established"
+	case HandshakeFailed:
+		return "failed"
+	}
+	return "none"
+}
+
+// PeerEvent reports that a handshake with Addr reached State.
+type PeerEvent struct {
+	Addr    string
+	State   HandshakeState
+	Inbound bool
+}
+
+// challenge is the nonce a node handed out in its VersionReply,
+// waiting for the initiator to sign it in a VerAck.
+type challenge struct {
+	nonce   []byte
+	key     *rsa.PublicKey
+	version uint32
+	ann     *address.Address
+	expires time.Time
+}
+
+// attempt is one handshake. done is closed once it is established or
+// has failed with err.
+type attempt struct {
+	state   HandshakeState
+	inbound bool
+	c       *challenge
+	err     error
+	done    chan struct{}
+	ended   time.Time
+}
+
+func (a *attempt) finished() bool {
+	return a.state == HandshakeEstablished || a.state == HandshakeFailed
+}
+
+// handshakes tracks the latest handshake the node initiated with each
+// address and the latest one each address initiated with it.
+type handshakes struct {
+	out     map[string]*attempt
+	in      map[string]*attempt
+	subs    []chan PeerEvent
+	stopped bool
+	sync.Mutex
+}
+
+func newHandshakes() *handshakes {
+	return &handshakes{out: make(map[string]*attempt), in: make(map[string]*attempt)}
+}
+
+// initiate returns the outbound handshake with addr in progress, or
+// starts a new one, reporting whether it did.
+func (hs *handshakes) initiate(addr string) (*attempt, bool) {
+	hs.Lock()
+	defer hs.Unlock()
+	if a := hs.out[addr]; a != nil && !a.finished() {
+		return a, false
+	}
+	hs.prune()
+	a := &attempt{state: HandshakeInitiated, done: make(chan struct{})}
+	hs.out[addr] = a
+	hs.notify(addr, a)
+	return a, true
+}
+
+// await records the challenge sent to addr in reply to its Version,
+// replacing an earlier one for the same key. While a challenge for
+// another key is pending it fails, so that no caller can cancel the
+// handshake of the node at addr by claiming its address. The
+// challenge fails once c expires.
+func (hs *handshakes) await(addr string, c *challenge) error {
+	hs.Lock()
+	defer hs.Unlock()
+	if old := hs.in[addr]; old != nil && !old.finished() {
+		if !old.c.key.Equal(c.key) {
+			return errors.New("another version from address is pending")
+		}
+		hs.finish(addr, old, errors.New("superseded by a new version"))
+	}
+	hs.prune()
+	a := &attempt{state: HandshakeAwaitingAck, inbound: true, c: c, done: make(chan struct{})}
+	hs.in[addr] = a
+	hs.notify(addr, a)
+	time.AfterFunc(time.Until(c.expires), func() {
+		hs.Lock()
+		defer hs.Unlock()
+		if !a.finished() {
+			hs.finish(addr, a, errors.New("version challenge expired"))
+		}
+	})
+	return nil
+}
+
+// initiated reports whether a handshake the node started with addr is
+// in progress.
+func (hs *handshakes) initiated(addr string) bool {
+	hs.Lock()
+	defer hs.Unlock()
+	a := hs.out[addr]
+	return a != nil && !a.finished()
+}
+
+// pending returns the inbound handshake with addr awaiting a VerAck,
+// along with its challenge.
+func (hs *handshakes) pending(addr string) (*attempt, *challenge) {
+	hs.Lock()
+	defer hs.Unlock()
+	a := hs.in[addr]
+	if a == nil || a.state != HandshakeAwaitingAck {
+		return nil, nil
+	}
+	return a, a.c
+}
+
+// complete ends a with err, unless it already ended.
+func (hs *handshakes) complete(addr string, a *attempt, err error) {
+	hs.Lock()
+	defer hs.Unlock()
+	if !a.finished() {
+		hs.finish(addr, a, err)
+	}
+}
+
+func (hs *handshakes) finish(addr string, a *attempt, err error) {
+	a.state = HandshakeEstablished
+	if err != nil {
+		a.state = HandshakeFailed
+	}
+	a.err = err
+	a.c = nil
+	a.ended = time.Now()
+	close(a.done)
+	hs.notify(addr, a)
+}
+
+// prune forgets handshakes that ended over a minute ago.
+func (hs *handshakes) prune() {
+	cutoff := time.Now().Add(-time.Minute)
+	for _, m := range []map[string]*attempt{hs.out, hs.in} {
+		for addr, a := range m {
+			if a.finished() && a.ended.Before(cutoff) {
+				delete(m, addr)
+			}
+		}
+	}
+}
+
+// state returns the state of the latest handshake with addr, giving
+// precedence to one in progress.
+func (hs *handshakes) state(addr string) HandshakeState {
+	hs.Lock()
+	defer hs.Unlock()
+	out, in := hs.out[addr], hs.in[addr]
+	switch {
+	case out == nil && in == nil:
+		return HandshakeNone
+	case in == nil:
+		return out.state
+	case out == nil:
+		return in.state
+	case !out.finished():
+		return out.state
+	case !in.finished(), in.ended.After(out.ended):
+		return in.state
+	}
+	return out.state
+}
+
+func (hs *handshakes) notify(addr string, a *attempt) {
+	for _, ch := range hs.subs {
+		select {
+		case ch <- PeerEvent{Addr: addr, State: a.state, Inbound: a.inbound}:
+		default:
+		}
+	}
+}
+
+// subscribe returns a channel receiving every handshake state change
+// and a function that unsubscribes it. Events are dropped for
+// subscribers that fall more than 64 events behind.
+func (hs *handshakes) subscribe() (<-chan PeerEvent, func()) {
+	hs.Lock()
+	defer hs.Unlock()
+	ch := make(chan PeerEvent, 64)
+	if hs.stopped {
+		close(ch)
+		return ch, func() {}
+	}
+	hs.subs = append(hs.subs, ch)
+	return ch, func() {
+		hs.Lock()
+		defer hs.Unlock()
+		for i, sub := range hs.subs {
+			if sub == ch {
+				hs.subs = append(hs.subs[:i], hs.subs[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+}
+
+// stop closes every subscriber channel.
+func (hs *handshakes) stop() {
+	hs.Lock()
+	defer hs.Unlock()
+	hs.stopped = true
+	for _, ch := range hs.subs {
+		close(ch)
+	}
+	hs.subs = nil
+}
+
+// SubscribePeers returns a channel receiving the state changes of the
+// node's handshakes. It is closed when the node is killed.
+func (n *Node) SubscribePeers() <-chan PeerEvent {
+	ch, _ := n.handshakes.subscribe()
+	return ch
+}
+
+// HandshakeState returns the state of the latest handshake with addr.
+func (n *Node) HandshakeState(addr string) HandshakeState {
+	return n.handshakes.state(addr)
+}
+
+// Connect starts a handshake with addr, or joins the one in progress,
+// and returns a channel receiving its outcome.
+func (n *Node) Connect(addr string) <-chan error {
+	a, fresh := n.handshakes.initiate(addr)
+	if fresh {
+		started := n.spawn(&n.workers, func() {
+			n.handshakes.complete(addr, a, n.runHandshake(addr))
+		})
+		if !started {
+			n.handshakes.complete(addr, a, ErrShutdown)
+		}
+	}
+	res := make(chan error, 1)
+	go func() {
+		<-a.done
+		res <- a.err
+	}()
+	return res
+}
+
+// WaitForPeer blocks until addr is an established peer, whichever side
+// started the handshake, or ctx is done.
+func (n *Node) WaitForPeer(ctx context.Context, addr string) error {
+	return n.waitFor(ctx, func() bool { return n.PeerDb.In(addr) })
+}
+
+// waitForID blocks until the node identified by fp is a peer or ctx is
+// done, and returns the peer.
+func (n *Node) waitForID(ctx context.Context, fp string) *peer.Peer {
+	var p *peer.Peer
+	_ = n.waitFor(ctx, func() bool {
+		p = n.peerByID(fp)
+		return p != nil
+	})
+	return p
+}
+
+// waitFor blocks until cond holds, checking it again after every
+// handshake that is established.
+func (n *Node) waitFor(ctx context.Context, cond func() bool) error {
+	events, cancel := n.handshakes.subscribe()
+	defer cancel()
+	if cond() {
+		return nil
+	}
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return errors.New("node stopped")
+			}
+			if ev.State == HandshakeEstablished && cond() {
+				return nil
+			}
+		case <-ctx.Done():
+			if cond() {
+				return nil
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// handshakeMsg is what signer signs to prove to verifier that it
+// holds its key. Binding both addresses keeps a signature from being
+// replayed in an exchange between other nodes.
+func handshakeMsg(nonce []byte, signer, verifier string) string {
+	return fmt.Sprintf("version:%x:%v:%v", nonce, signer, verifier)
+}
+
+// dialBackMsg is what signer signs in a DialBack. It differs from
+// handshakeMsg so neither signature can stand in for the other.
+func dialBackMsg(nonce []byte, signer, verifier string) string {
+	return fmt.Sprintf("dialback:%x:%v:%v", nonce, signer, verifier)
+}
+
+// dialBack challenges the node at addr, the address an initiator
+// claimed in its Version, and checks it answers with key. Otherwise a
+// node could take the place of another in the peer database by
+// claiming its address.
+func (n *Node) dialBack(ctx context.Context, addr string, key *rsa.PublicKey) error {
+	nonce, err := utils.RandomBytes(nonceSize)
+	if err != nil {
+		return err
+	}
+	reply, err := address.New(addr, 0).DialBackRPC(n.ctx, n.Conns, &proto.DialBackRequest{
+		AddrMe: n.Addr,
+		Nonce:  nonce,
+	})
+	if err != nil {
+		return fmt.Errorf("dial back to sender address failed: %v", err)
+	}
+	if !utils.Verify(key, dialBackMsg(nonce, addr, n.Addr), reply.Sig) {
+		n.misbehaving(ctx, scoreBadHandshake, "sender address held by another key")
+		return errors.New("sender address held by another key")
+	}
+	return nil
+}
+
+// handshake connects to addr and waits for the outcome.
+func (n *Node) handshake(addr string) error {
+	return <-n.Connect(addr)
+}
+
+// runHandshake runs the initiator side of the Version exchange with
+// the node at addr. Each side signs a fresh nonce chosen by the other,
+// and addr is only added as a peer once its signature has been
+// verified.
+func (n *Node) runHandshake(addr string) error {
+	hp, err := netaddr.Parse(addr)
+	if err != nil || !hp.Dialable() {
+		return errors.New("invalid peer address")
+	}
+	if n.PeerDb.IsBanned(hp.Host) {
+		return errors.New("peer address is banned")
+	}
+	nonce, err := utils.RandomBytes(nonceSize)
+	if err != nil {
+		return err
+	}
+	key, err := utils.EncodePublicKey(&n.Id.PrivateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	ann, err := address.Announce(n.Id.PrivateKey, n.Addr)
+	if err != nil {
+		return err
+	}
+	sel, _ := n.AddrDb.(addressdb.Selector)
+	if sel != nil {
+		sel.Attempt(addr)
+	}
+	a := address.New(addr, 0)
+	reply, err := a.VersionRPC(n.ctx, n.Conns, &proto.VersionRequest{
+		Version:      uint32(n.Conf.Version),
+		AddrYou:      addr,
+		AddrMe:       n.Addr,
+		SerPk:        key,
+		Nonce:        nonce,
+		Announcement: ann.Serialize(),
+	})
+	if err != nil {
+		return err
+	}
+	theirKey, err := utils.DecodePublicKey(reply.SerPk)
+	if err != nil {
+		return err
+	}
+	if fp, err := id.Fingerprint(theirKey); err != nil || n.PeerDb.IsBanned(fp) {
+		return errors.New("peer key is banned")
+	}
+	if !utils.Verify(theirKey, handshakeMsg(nonce, addr, n.Addr), reply.Sig) {
+		return errors.New("invalid version signature")
+	}
+	sig, err := utils.Sign(n.Id.PrivateKey, handshakeMsg(reply.Nonce, n.Addr, addr))
+	if err != nil {
+		return err
+	}
+	if _, err := a.VerAckRPC(n.ctx, n.Conns, &proto.VersionAck{AddrMe: n.Addr, Sig: sig}); err != nil {
+		return err
+	}
+	if !n.addPeer(addr, uint32(n.Conf.Version), theirKey, announcement(reply.Announcement, addr, theirKey), false) {
+		if !n.PeerDb.In(addr) {
+			return errors.New("no outbound peer slots")
+		}
+		return nil
+	}
+	if sel != nil {
+		sel.Good(addr)
+	}
+	return nil
+}
+
+// announcement returns the address record a node sent about itself
+// during the handshake, or nil unless it is valid for addr and key.
+func announcement(in *proto.Address, addr string, key *rsa.PublicKey) *address.Address {
+	if in == nil {
+		return nil
+	}
+	a := address.Deserialize(in)
+	if a.Addr != addr || !a.SignedBy(key) || a.Verify() != nil {
+		return nil
+	}
+	return a
+}
+
+// addPeer records a peer whose key has been verified, along with the
+// record it signed for its address if it sent one. inbound is set if
+// the peer started the handshake.
+func (n *Node) addPeer(addr string, version uint32, key *rsa.PublicKey, ann *address.Address, inbound bool) bool {
+	rec := address.New(addr, time.Now().UnixNano())
+	if ann != nil {
+		ann.LastSeen = rec.LastSeen
+		rec = ann
+	}
+	if old := n.AddrDb.Get(addr); old != nil && ann == nil {
+		_ = n.AddrDb.UpdateLastSeen(addr, rec.LastSeen)
+	} else {
+		// A collision in the address table does not keep out a peer
+		// whose key was just verified.
+		_ = n.AddrDb.Put(rec)
+	}
+	if stored := n.AddrDb.Get(addr); stored != nil {
+		rec = stored
+	}
+	p := peer.New(rec, version, key)
+	p.Inbound = inbound
+	p.Static = n.static.has(addr)
+	evicted, ok := n.PeerDb.Add(p)
+	if !ok {
+		return false
+	}
+	if evicted != nil {
+		utils.Debug.Printf("%v evicted %v to make room for %v",
+			utils.FmtAddr(n.Addr), utils.FmtAddr(evicted.Addr.Addr), utils.FmtAddr(addr))
+		n.Conns.Drop(evicted.Addr.Addr)
+	}
+	if kid, err := dht.KeyID(key); err == nil {
+		n.DHT.Update(dht.Contact{ID: kid, Addr: addr})
+	}
+	n.Group.SetOffline(addr, false)
+	return true
+}
@@ -0,0 +1,165 @@
+package id
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"math/big"
+	"time"
+)
+
+const certValidity = 365 * 24 * time.Hour
+
+func newTemplate(pk *rsa.PublicKey, isCA bool) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	fp, err := Fingerprint(pk)
+	if err != nil {
+		return nil, err
+	}
+	t := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: fp},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if isCA {
+		t.IsCA = true
+		t.BasicConstraintsValid = true
+		t.KeyUsage |= x509.KeyUsageCertSign
+	}
+	return t, nil
+}
+
+// SelfSignedCert returns a DER certificate for sk's public key signed
+// by sk itself.
+func SelfSignedCert(sk *rsa.PrivateKey) ([]byte, error) {
+	return selfSigned(sk, false)
+}
+
+// CACert returns a self-signed DER certificate for sk's public key
+// that may be used to issue others.
+func CACert(sk *rsa.PrivateKey) ([]byte, error) {
+	return selfSigned(sk, true)
+}
+
+func selfSigned(sk *rsa.PrivateKey, isCA bool) ([]byte, error) {
+	t, err := newTemplate(&sk.PublicKey, isCA)
+	if err != nil {
+		return nil, err
+	}
+	return x509.CreateCertificate(rand.Reader, t, t, &sk.PublicKey, sk)
+}
+
+// IssueCert returns a DER certificate for pk signed by the CA whose
+// key is caSk and whose certificate is caDER.
+func IssueCert(caSk *rsa.PrivateKey, caDER []byte, pk *rsa.PublicKey) ([]byte, error) {
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+	t, err := newTemplate(pk, false)
+	if err != nil {
+		return nil, err
+	}
+	return x509.CreateCertificate(rand.Reader, t, ca, pk, caSk)
+}
+
+// VerifyChain checks a certificate chain presented by a peer and
+// returns the leaf's public key. The chain is either a single
+// self-signed certificate or a leaf followed by the self-signed CA
+// certificate of its issuer. When trustedCA is non-nil the chain must
+// have been issued by that key (or be the CA's own certificate).
+func VerifyChain(rawCerts [][]byte, trustedCA *rsa.PublicKey) (*rsa.PublicKey, error) {
+	if len(rawCerts) == 0 || len(rawCerts) > 2 {
+		return nil, errors.New("unexpected certificate chain length")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		c, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		if now.Before(c.NotBefore) || now.After(c.NotAfter) {
+			return nil, errors.New("certificate expired or not yet valid")
+		}
+		certs[i] = c
+	}
+	leaf, issuer := certs[0], certs[len(certs)-1]
+	// A node's own certificate is self-signed without being a CA, so
+	// only the signature is checked here. CheckSignatureFrom also
+	// requires the issuer of a leaf to be a CA.
+	if err := issuer.CheckSignature(issuer.SignatureAlgorithm, issuer.RawTBSCertificate, issuer.Signature); err != nil {
+		return nil, err
+	}
+	if leaf != issuer {
+		if err := leaf.CheckSignatureFrom(issuer); err != nil {
+			return nil, err
+		}
+	}
+	if trustedCA != nil {
+		caPk, ok := issuer.PublicKey.(*rsa.PublicKey)
+		if !ok || !caPk.Equal(trustedCA) {
+			return nil, errors.New("certificate not issued by trusted CA")
+		}
+	}
+	pk, ok := leaf.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("certificate key is not an RSA key")
+	}
+	return pk, nil
+}
+
+// TLSConfig returns a config usable by both the server and the client
+// side of a connection. Both sides present the ID's current
+// certificate and require the other side to present a chain accepted
+// by VerifyChain.
+func (i *ID) TLSConfig(trustedCA *rsa.PublicKey) *tls.Config {
+	verify := func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		_, err := VerifyChain(rawCerts, trustedCA)
+		return err
+	}
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return i.TLSCertificate(), nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return i.TLSCertificate(), nil
+		},
+		ClientAuth: tls.RequireAnyClientCert,
+		// Peers are identified by key rather than by host name, so
+		// the standard verification is replaced by VerifyChain.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verify,
+		MinVersion:            tls.VersionTLS12,
+	}
+}
+
+// PeerKey returns the public key of the peer that sent the RPC
+// handled under ctx, as proven during the TLS handshake.
+func PeerKey(ctx context.Context) (*rsa.PublicKey, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return nil, errors.New("no peer information in context")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return nil, errors.New("peer is not authenticated")
+	}
+	pk, ok := info.State.PeerCertificates[0].PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("peer key is not an RSA key")
+	}
+	return pk, nil
+}
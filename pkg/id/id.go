@@ -2,12 +2,26 @@ package id
 
 import (
 	"crypto/rsa"
+	"crypto/tls"
 	"finalbruh/pkg/utils"
+	"sync"
 )
 
 type ID struct {
-	PrivateKey  *rsa.PrivateKey
-	Certificate string
+	PrivateKey *rsa.PrivateKey
+
+	// certificate is a CA's signature over the encoded public key.
+	certificate string
+
+	// selfCert is the node's self-signed DER certificate, issued and
+	// caCert are set once a CA has issued a certificate for the key.
+	selfCert []byte
+	issued   []byte
+	caCert   []byte
+	// ownCA is the certificate the ID issues others' under, made the
+	// first time it is needed.
+	ownCA []byte
+	mu    sync.RWMutex
 }
 
 func New() (*ID, error) {
@@ -15,8 +29,80 @@ func New() (*ID, error) {
 	if err != nil {
 		return nil, err
 	}
+	self, err := SelfSignedCert(sk)
+	if err != nil {
+		return nil, err
+	}
 	id := &ID{
 		PrivateKey: sk,
+		selfCert:   self,
 	}
 	return id, nil
 }
+
+// SelfCert returns the ID's self-signed DER certificate.
+func (i *ID) SelfCert() []byte {
+	return i.selfCert
+}
+
+// CACert returns the ID's self-signed CA certificate, which issues the
+// certificates of the nodes registering with it.
+func (i *ID) CACert() ([]byte, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.ownCA == nil {
+		der, err := CACert(i.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		i.ownCA = der
+	}
+	return i.ownCA, nil
+}
+
+// Certificate returns the CA's signature over the ID's key, or "" if
+// the ID is not registered with a CA.
+func (i *ID) Certificate() string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.certificate
+}
+
+func (i *ID) SetCertificate(cert string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.certificate = cert
+}
+
+// SetIssuedCert records a certificate for the ID's key issued by the
+// CA whose certificate is caDER. It is presented on new connections
+// from then on.
+func (i *ID) SetIssuedCert(der, caDER []byte) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.issued = der
+	i.caCert = caDER
+}
+
+// TLSCertificate returns the chain the ID presents during a TLS
+// handshake: the CA-issued certificate if there is one, otherwise the
+// self-signed certificate.
+func (i *ID) TLSCertificate() *tls.Certificate {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	chain := [][]byte{i.selfCert}
+	if i.issued != nil {
+		chain = [][]byte{i.issued, i.caCert}
+	}
+	return &tls.Certificate{Certificate: chain, PrivateKey: i.PrivateKey}
+}
+
+// Fingerprint returns the hex SHA-256 hash of the encoded public key,
+// which identifies a node independently of its address.
+func Fingerprint(pk *rsa.PublicKey) (string, error) {
+	encoded, err := utils.EncodePublicKey(pk)
+	if err != nil {
+		return "", err
+	}
+	return utils.Hash([]byte(encoded)), nil
+}
@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"finalbruh/pkg/address"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
+	"sync"
+	"time"
+)
+
+// replies remembers the outcome of RPCs that carried an idempotency
+// key so that retries of them are answered without applying them
+// again.
+type replies struct {
+	window  time.Duration
+	entries map[string]*reply
+	swept   time.Time
+	sync.Mutex
+}
+
+type reply struct {
+	digest  [sha256.Size]byte
+	done    chan struct{}
+	resp    interface{}
+	err     error
+	expires time.Time
+}
+
+func newReplies(window time.Duration) *replies {
+	return &replies{window: window, entries: make(map[string]*reply)}
+}
+
+// claim returns the entry for key and whether the caller must run the
+// RPC, whose request hashes to digest, and then call finish. Otherwise
+// the entry belongs to an earlier attempt.
+func (r *replies) claim(key string, digest [sha256.Size]byte) (*reply, bool) {
+	r.Lock()
+	defer r.Unlock()
+	// Sweeping at most every tenth of the window keeps claims cheap
+	// while bounding how long expired entries linger.
+	if now := time.Now(); now.Sub(r.swept) > r.window/10 {
+		for k, e := range r.entries {
+			if !e.expires.IsZero() && now.After(e.expires) {
+				delete(r.entries, k)
+			}
+		}
+		r.swept = now
+	}
+	if e := r.entries[key]; e != nil {
+		return e, false
+	}
+	e := &reply{digest: digest, done: make(chan struct{})}
+	r.entries[key] = e
+	return e, true
+}
+
+// finish records the outcome of the RPC for key. Failed RPCs are
+// forgotten so that a retry runs them again.
+func (r *replies) finish(key string, e *reply, resp interface{}, err error) {
+	r.Lock()
+	defer r.Unlock()
+	e.resp, e.err = resp, err
+	if err != nil {
+		delete(r.entries, key)
+	} else {
+		e.expires = time.Now().Add(r.window)
+	}
+	close(e.done)
+}
+
+// requestDigest hashes the serialized request, so that a key reused for
+// another request is not answered with the first one's reply.
+func requestDigest(req interface{}) ([sha256.Size]byte, error) {
+	m, ok := req.(gproto.Message)
+	if !ok {
+		return [sha256.Size]byte{}, nil
+	}
+	b, err := gproto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// idempotencyInterceptor applies an RPC carrying an idempotency key at
+// most once per caller within IdempotencyWindow, answering repeats with
+// the reply to the first. A repeat with another request is refused.
+func (n *Node) idempotencyInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(address.IdempotencyKey)
+	if len(keys) == 0 || n.Conf.IdempotencyWindow <= 0 {
+		return handler(ctx, req)
+	}
+	digest, err := requestDigest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "unserializable request")
+	}
+	key := n.callerID(ctx) + " " + info.FullMethod + " " + keys[0]
+	e, owner := n.replies.claim(key, digest)
+	if !owner {
+		if e.digest != digest {
+			return nil, status.Error(codes.InvalidArgument, "idempotency key reused for another request")
+		}
+		select {
+		case <-e.done:
+			return e.resp, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	resp, err := handler(ctx, req)
+	n.replies.finish(key, e, resp, err)
+	return resp, err
+}
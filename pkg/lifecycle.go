@@ -0,0 +1,193 @@
+package pkg
+
+import (
+	"errors"
+	"finalbruh/pkg/netaddr"
+	"finalbruh/pkg/utils"
+	"golang.org/x/net/context"
+	"sync"
+)
+
+// ErrShutdown is returned for work refused because the node is
+// shutting down.
+var ErrShutdown = errors.New("node is shutting down")
+
+// Start binds the node's listener, serves RPCs and starts the
+// background work. It fails if the node could not be set up, as when
+// the databases in DataDir could not be loaded or TrustedCA could not
+// be decoded. The node runs until Shutdown is called, serving
+// fails or ctx is cancelled; in the last case in-flight messages are
+// abandoned rather than drained.
+func (n *Node) Start(ctx context.Context) error {
+	if n.initErr != nil {
+		return n.initErr
+	}
+	listen, err := n.Conf.listenAddr()
+	if err != nil {
+		return err
+	}
+	lis, err := n.listen(listen.String())
+	if err != nil {
+		return err
+	}
+	bound, err := netaddr.Parse(n.listenAddr)
+	if err != nil {
+		lis.Close()
+		return err
+	}
+	adv, err := n.Conf.advertiseAddr(bound)
+	if err != nil {
+		lis.Close()
+		return err
+	}
+	// The address is settled before the first request is served, as
+	// handlers read it without locking.
+	n.Addr = adv.String()
+	n.PeerDb.SetAddr(n.Addr)
+	cancel := n.cancel
+	n.ctx, n.cancel = context.WithCancel(ctx)
+	cancel()
+	n.started = true
+	n.serve(lis)
+	events := n.swim.Subscribe()
+	n.spawn(&n.workers, func() { n.handleLiveness(events) })
+	n.swim.Start()
+	n.spawn(&n.workers, n.maintain)
+	go func() {
+		select {
+		case <-n.ctx.Done():
+			_ = n.Shutdown(context.Background())
+		case <-n.done:
+		}
+	}()
+	utils.Debug.Printf("%v started", utils.FmtAddr(n.Addr))
+	return nil
+}
+
+// spawn runs f in a goroutine that Shutdown waits for as part of wg,
+// unless the node is shutting down. It reports whether f was started.
+func (n *Node) spawn(wg *sync.WaitGroup, f func()) bool {
+	n.lifeMu.Lock()
+	defer n.lifeMu.Unlock()
+	if n.closing {
+		return false
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+	return true
+}
+
+// shuttingDown reports whether Shutdown has been called.
+func (n *Node) shuttingDown() bool {
+	n.lifeMu.Lock()
+	defer n.lifeMu.Unlock()
+	return n.closing
+}
+
+// fail shuts the node down because of err, which Err then returns.
+func (n *Node) fail(err error) {
+	n.lifeMu.Lock()
+	if n.err == nil {
+		n.err = err
+	}
+	n.lifeMu.Unlock()
+	// Shutdown waits for the caller, which may be a tracked goroutine.
+	go n.Shutdown(context.Background())
+}
+
+// Shutdown stops the node. It stops accepting RPCs, lets in-flight
+// requests and outbound messages finish, then cancels the background
+// work, waits for it and saves the databases. If ctx is done before
+// the messages are sent they are abandoned and ctx's error returned.
+// A node that did not start is not saved, so a failed Start leaves
+// DataDir as it was. Concurrent and later calls wait for the first to
+// finish.
+func (n *Node) Shutdown(ctx context.Context) error {
+	first := false
+	n.stopOnce.Do(func() { first = true })
+	if !first {
+		select {
+		case <-n.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer close(n.done)
+	n.lifeMu.Lock()
+	n.closing = true
+	n.lifeMu.Unlock()
+
+	var err error
+	if n.started {
+		drained := make(chan struct{})
+		go func() {
+			n.Server.GracefulStop()
+			n.outbound.Close()
+			n.outbound.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			err = ctx.Err()
+			n.Server.Stop()
+		}
+		n.cancel()
+		<-drained
+		n.swim.Stop()
+		n.workers.Wait()
+	}
+	n.cancel()
+	n.handshakes.stop()
+	if cerr := n.Conns.Close(); cerr != nil {
+		utils.Err.Printf("%v received error when closing connections",
+			utils.FmtAddr(n.Addr))
+	}
+	if !n.started {
+		n.AddrDb.Discard()
+		n.PeerDb.Discard()
+		utils.Debug.Printf("%v shut down", utils.FmtAddr(n.Addr))
+		return err
+	}
+	if cerr := n.AddrDb.Close(); cerr != nil {
+		utils.Err.Printf("%v received error when saving addresses: %v",
+			utils.FmtAddr(n.Addr), cerr)
+		if err == nil {
+			err = cerr
+		}
+	}
+	if cerr := n.PeerDb.Close(); cerr != nil {
+		utils.Err.Printf("%v received error when saving peers: %v",
+			utils.FmtAddr(n.Addr), cerr)
+		if err == nil {
+			err = cerr
+		}
+	}
+	utils.Debug.Printf("%v shut down", utils.FmtAddr(n.Addr))
+	return err
+}
+
+// Kill shuts the node down, waiting as long as it takes.
+func (n *Node) Kill() {
+	if err := n.Shutdown(context.Background()); err != nil {
+		utils.Err.Printf("%v received error when shutting down: %v",
+			utils.FmtAddr(n.Addr), err)
+	}
+}
+
+// Done returns a channel closed once the node has shut down.
+func (n *Node) Done() <-chan struct{} {
+	return n.done
+}
+
+// Err returns the error that made the node shut down by itself, or nil
+// if it was shut down deliberately or is still running.
+func (n *Node) Err() error {
+	n.lifeMu.Lock()
+	defer n.lifeMu.Unlock()
+	return n.err
+}
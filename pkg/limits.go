@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"finalbruh/pkg/id"
+	"finalbruh/pkg/netaddr"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodCost is the number of rate limit tokens an RPC consumes.
+// RPCs that make the node perform RSA operations cost more.
+var methodCost = map[string]float64{
+	"/BrunoCoin/Version":    5,
+	"/BrunoCoin/VerAck":     5,
+	"/BrunoCoin/DialBack":   5,
+	"/BrunoCoin/Register":   10,
+	"/BrunoCoin/AddMember":  5,
+	"/BrunoCoin/KickMember": 5,
+	"/BrunoCoin/Store":      5,
+}
+
+// callerID identifies the node behind an inbound RPC: by the key it
+// proved during the TLS handshake, or by its remote host otherwise.
+func (n *Node) callerID(ctx context.Context) string {
+	if pk, err := n.peerKey(ctx); err == nil && pk != nil {
+		if fp, err := id.Fingerprint(pk); err == nil {
+			return fp
+		}
+	}
+	return remoteHost(ctx)
+}
+
+// remoteHost returns the host an inbound RPC came from.
+func remoteHost(ctx context.Context) string {
+	p, ok := grpcpeer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if hp, err := netaddr.Parse(p.Addr.String()); err == nil {
+		return hp.Host
+	}
+	return p.Addr.String()
+}
+
+// limitInterceptor rejects RPCs once the caller, its host or the node
+// as a whole exceeds its configured rate.
+func (n *Node) limitInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	cost, ok := methodCost[info.FullMethod]
+	if !ok {
+		cost = 1
+	}
+	if !n.limiter.AllowFrom(n.callerID(ctx), remoteHost(ctx), cost) {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/swim"
+	"finalbruh/pkg/utils"
+	"golang.org/x/net/context"
+	"sync/atomic"
+	"time"
+)
+
+// prober sends the failure detector's probes over the node's
+// connections.
+type prober struct {
+	n   *Node
+	seq uint64
+}
+
+func (p *prober) Ping(ctx context.Context, addr string) error {
+	_, err := address.New(addr, 0).PingRPC(ctx, p.n.Conns, &proto.PingRequest{
+		AddrMe: p.n.Addr,
+		Seq:    atomic.AddUint64(&p.seq, 1),
+	})
+	if err == nil {
+		_ = p.n.PeerDb.UpdateLastSeen(addr, time.Now().UnixNano())
+	}
+	return err
+}
+
+func (p *prober) PingReq(ctx context.Context, via, target string) error {
+	_, err := address.New(via, 0).PingReqRPC(ctx, p.n.Conns, &proto.PingReqRequest{
+		AddrMe: p.n.Addr,
+		Target: target,
+		Seq:    atomic.AddUint64(&p.seq, 1),
+	})
+	return err
+}
+
+func (n *Node) peerAddrs() []string {
+	var addrs []string
+	for _, p := range n.PeerDb.List() {
+		addrs = append(addrs, p.Addr.Addr)
+	}
+	return addrs
+}
+
+// Liveness returns a channel of peer state changes reported by the
+// failure detector. It is closed when the node is killed.
+func (n *Node) Liveness() <-chan swim.Event {
+	return n.swim.Subscribe()
+}
+
+// handleLiveness evicts dead peers and tracks which group members are
+// offline until the detector's channel is closed.
+func (n *Node) handleLiveness(events <-chan swim.Event) {
+	for ev := range events {
+		switch ev.State {
+		case swim.Suspect:
+			utils.Debug.Printf("%v suspects %v", utils.FmtAddr(n.Addr), utils.FmtAddr(ev.Addr))
+		case swim.Dead:
+			utils.Debug.Printf("%v declared %v dead", utils.FmtAddr(n.Addr), utils.FmtAddr(ev.Addr))
+			n.PeerDb.Remove(ev.Addr)
+			n.Conns.Drop(ev.Addr)
+			n.Group.SetOffline(ev.Addr, true)
+		case swim.Alive:
+			n.Group.SetOffline(ev.Addr, false)
+		}
+	}
+}
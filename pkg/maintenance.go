@@ -0,0 +1,191 @@
+package pkg
+
+import (
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/address/addressdb"
+	"finalbruh/pkg/netaddr"
+	"finalbruh/pkg/peer"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/utils"
+	"math/rand"
+	"time"
+)
+
+// maxDialsPerRound bounds how many new peers the maintenance loop
+// tries to connect to in one round.
+const maxDialsPerRound = 8
+
+// maintain starts keeping the static peers connected, reconnects to
+// the peers restored from the data directory, connects to the seeds
+// and publishes the node's DHT record. Then, every
+// MaintenanceInterval, it tops the outbound peers up towards PeerLimit
+// and looks for offline group members at new addresses. Every
+// BroadcastInterval it re-announces the node's address, every
+// RepublishInterval its record, and every AddrPruneInterval it prunes
+// stale addresses; an interval of 0 disables that task. It returns
+// once the node shuts down.
+func (n *Node) maintain() {
+	n.keepStaticPeers()
+	n.reconnectPeers()
+	n.connectToSeeds()
+	n.publish()
+	maintTicks, stopMaint := every(n.Conf.MaintenanceInterval)
+	defer stopMaint()
+	bcastTicks, stopBcast := every(n.Conf.BroadcastInterval)
+	defer stopBcast()
+	pubTicks, stopPub := every(n.Conf.RepublishInterval)
+	defer stopPub()
+	pruneTicks, stopPrune := every(n.Conf.AddrPruneInterval)
+	defer stopPrune()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-maintTicks:
+			n.fillPeers()
+			n.findMovedMembers()
+		case <-bcastTicks:
+			n.BroadcastAddr()
+		case <-pubTicks:
+			n.publish()
+		case <-pruneTicks:
+			n.pruneAddrs()
+		}
+	}
+}
+
+// every returns a channel that ticks every d and a function stopping
+// it. An interval of 0 disables the ticks: the channel is nil.
+func every(d time.Duration) (<-chan time.Time, func()) {
+	if d <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// reconnectPeers repeats the handshake with the outbound peers a
+// restarted node loaded from disk, forgetting those that do not
+// answer. Inbound peers are left to dial back; the failure detector
+// evicts those that are gone.
+func (n *Node) reconnectPeers() {
+	for _, p := range n.PeerDb.List() {
+		if p.Inbound {
+			continue
+		}
+		if err := n.handshake(p.Addr.Addr); err != nil {
+			n.PeerDb.Remove(p.Addr.Addr)
+		}
+	}
+}
+
+// pruneAddrs forgets the addresses not heard of for AddrMaxAge, other
+// than those of current peers.
+func (n *Node) pruneAddrs() {
+	if pruned := n.AddrDb.Prune(n.Conf.AddrMaxAge, n.PeerDb.In); pruned > 0 {
+		utils.Debug.Printf("%v pruned %v stale addresses",
+			utils.FmtAddr(n.Addr), pruned)
+	}
+}
+
+func (n *Node) connectToSeeds() {
+	for _, seed := range n.Conf.Seeds {
+		if seed != n.Addr && !n.PeerDb.In(seed) {
+			n.ConnectToPeer(seed)
+		}
+	}
+}
+
+// fillPeers learns addresses from a random peer and connects to known
+// addresses until the node has PeerLimit outbound peers. A node left
+// without peers goes back to its seeds.
+func (n *Node) fillPeers() {
+	peers := n.PeerDb.List()
+	if outbound(peers) >= n.Conf.PeerLimit {
+		return
+	}
+	if len(peers) == 0 {
+		n.connectToSeeds()
+		return
+	}
+	n.learnAddresses(peers[rand.Intn(len(peers))].Addr)
+
+	candidates := n.dialCandidates(peers)
+	dials := 0
+	for _, a := range candidates {
+		if outbound(n.PeerDb.List()) >= n.Conf.PeerLimit || dials >= maxDialsPerRound {
+			return
+		}
+		if a.Addr == n.Addr || n.PeerDb.In(a.Addr) {
+			continue
+		}
+		dials++
+		n.ConnectToPeer(a.Addr)
+	}
+}
+
+// outbound counts the peers taking an outbound slot.
+func outbound(peers []*peer.Peer) int {
+	count := 0
+	for _, p := range peers {
+		if !p.Inbound && !p.Static {
+			count++
+		}
+	}
+	return count
+}
+
+// dialCandidates returns addresses to try connecting to, chosen by the
+// address database if it can, otherwise at random.
+func (n *Node) dialCandidates(peers []*peer.Peer) []*address.Address {
+	if sel, ok := n.AddrDb.(addressdb.Selector); ok {
+		var exclude []string
+		for _, p := range peers {
+			exclude = append(exclude, p.Addr.Addr)
+		}
+		// Ask for spares since some candidates are already peers.
+		return sel.Select(2*maxDialsPerRound+len(peers), exclude)
+	}
+	candidates := n.AddrDb.List()
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return candidates
+}
+
+// learnAddresses stores the addresses known to the peer at a without
+// relaying them.
+func (n *Node) learnAddresses(a *address.Address) {
+	reply, err := a.GetAddressesRPC(n.ctx, n.Conns, &proto.GetAddressesRequest{})
+	if err != nil {
+		utils.Debug.Printf("%v recieved no response from GetAddressesRPC to %v",
+			utils.FmtAddr(n.Addr), utils.FmtAddr(a.Addr))
+		return
+	}
+	for _, addr := range reply.Addrs {
+		if addr.Addr == n.Addr || n.AddrDb.Get(addr.Addr) != nil {
+			continue
+		}
+		if hp, err := netaddr.Parse(addr.Addr); err != nil || !hp.Dialable() {
+			continue
+		}
+		newAddr := address.Deserialize(addr)
+		if n.checkAddr(newAddr) != nil {
+			continue
+		}
+		if newAddr.Signed() {
+			newAddr.LastSeen = newAddr.Timestamp
+		}
+		_ = n.addAddr(newAddr, a.Addr)
+	}
+}
+
+// findMovedMembers looks up the group members the failure detector
+// declared offline, in case they came back at another address.
+func (n *Node) findMovedMembers() {
+	for _, p := range n.Group.Members() {
+		if n.Group.IsOffline(p.Addr.Addr) {
+			_, _ = n.relocate(p)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"finalbruh/pkg/peer"
+	"finalbruh/pkg/utils"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Misbehavior scores for protocol violations. A caller is banned once
+// its total reaches Config.BanThreshold.
+const (
+	scoreBadSignature  = 50
+	scoreUndecryptable = 20
+	scoreMalformed     = 20
+	scoreAddrSpam      = 20
+	scoreBadHandshake  = 10
+	// A group message may fail to decrypt just because the sender
+	// missed a key rotation, so it barely counts.
+	scoreBadGroupMessage = 1
+)
+
+// misbehaving records a protocol violation by the caller of the RPC
+// handled under ctx. Its host is charged as well, so a caller cannot
+// shed its score by changing keys.
+func (n *Node) misbehaving(ctx context.Context, score int, reason string) {
+	id, host := n.callerID(ctx), remoteHost(ctx)
+	if id != "" && n.PeerDb.Misbehaving(id, score) {
+		utils.Debug.Printf("%v banned %v: %v", utils.FmtAddr(n.Addr), id, reason)
+	}
+	if host != "" && host != id && n.PeerDb.HostMisbehaving(host, score) {
+		utils.Debug.Printf("%v banned host %v: %v", utils.FmtAddr(n.Addr), host, reason)
+	}
+}
+
+// banInterceptor refuses every RPC from a caller whose key or host is
+// banned.
+func (n *Node) banInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	for _, id := range []string{n.callerID(ctx), remoteHost(ctx)} {
+		if id != "" && n.PeerDb.IsBanned(id) {
+			return nil, status.Error(codes.PermissionDenied, "banned")
+		}
+	}
+	return handler(ctx, req)
+}
+
+// Ban refuses RPCs from id, a key fingerprint or host, for d and
+// disconnects any peer it identifies.
+func (n *Node) Ban(id string, d time.Duration) {
+	n.PeerDb.Ban(id, time.Now().Add(d))
+}
+
+// Unban lifts a ban and resets the misbehavior score of id.
+func (n *Node) Unban(id string) {
+	n.PeerDb.Unban(id)
+}
+
+// Bans lists the bans currently in force.
+func (n *Node) Bans() []peer.Ban {
+	return n.PeerDb.Bans()
+}
@@ -0,0 +1,78 @@
+package netaddr
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// HostPort is a validated network address. Host is a host name, an
+// IPv4 literal or an IPv6 literal (without brackets), and may be empty
+// to mean every local interface.
+type HostPort struct {
+	Host string
+	Port int
+}
+
+// Parse validates s, which must be of the form host:port, [ipv6]:port
+// or :port.
+func Parse(s string) (HostPort, error) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return HostPort{}, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return HostPort{}, errors.New("invalid port in address " + s)
+	}
+	ip := host
+	if i := strings.LastIndexByte(ip, '%'); i >= 0 {
+		ip = ip[:i]
+	}
+	if strings.Contains(host, ":") && net.ParseIP(ip) == nil {
+		return HostPort{}, errors.New("invalid IPv6 literal in address " + s)
+	}
+	if strings.ContainsAny(host, " /[]") {
+		return HostPort{}, errors.New("invalid host in address " + s)
+	}
+	return HostPort{Host: host, Port: port}, nil
+}
+
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host, strconv.Itoa(hp.Port))
+}
+
+// IsUnspecified reports whether the host does not name a single
+// interface, i.e. it is empty, 0.0.0.0 or ::.
+func (hp HostPort) IsUnspecified() bool {
+	if hp.Host == "" {
+		return true
+	}
+	ip := net.ParseIP(hp.Host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// Dialable reports whether the address can be dialed by a peer: it
+// must name a host and a non-zero port.
+func (hp HostPort) Dialable() bool {
+	return !hp.IsUnspecified() && hp.Port != 0
+}
+
+// Group returns the network group of the host: its /16 for IPv4, its
+// /32 for IPv6 and the host name itself otherwise. Hosts in one group
+// are likely run by one operator.
+func (hp HostPort) Group() string {
+	host := hp.Host
+	if i := strings.LastIndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return strings.ToLower(host)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(16, 32)).String() + "/16"
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String() + "/32"
+}
@@ -1,19 +1,28 @@
 package pkg
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
 	"finalbruh/pkg/address"
 	"finalbruh/pkg/address/addressdb"
+	"finalbruh/pkg/dht"
+	"finalbruh/pkg/dispatch"
 	"finalbruh/pkg/group"
 	"finalbruh/pkg/id"
 	"finalbruh/pkg/peer"
 	"finalbruh/pkg/proto"
+	"finalbruh/pkg/ratelimit"
+	"finalbruh/pkg/swim"
+	"finalbruh/pkg/transport"
 	"finalbruh/pkg/utils"
 	"fmt"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"net"
-	"os"
-	"time"
+	"path/filepath"
+	"sync"
 )
 
 type Node struct {
@@ -24,43 +33,146 @@ type Node struct {
 	Addr string
 	Id   *id.ID
 
+	// listenAddr is the address the server is bound to.
+	listenAddr string
+
 	fGetAddr bool
 
 	AddrDb addressdb.AddressDb
 	PeerDb peer.PeerDb
+	Conns  *address.ConnManager
+	DHT    *dht.DHT
 
-	Group group.Group
+	Group *group.Group
 
 	Paused bool
+
+	handshakes *handshakes
+	static     *staticPeers
+	replies    *replies
+	limiter    *ratelimit.Limiter
+	gossipSem  chan struct{}
+	swim       *swim.Detector
+
+	// outbound sends messages to other nodes from a bounded pool of
+	// workers.
+	outbound *dispatch.Dispatcher
+
+	// initErr is the first error setting the node up, such as loading
+	// the databases from DataDir, which Start returns.
+	initErr error
+
+	// ctx is cancelled when the node shuts down. Shutdown waits for
+	// the goroutines started with spawn and for outbound to drain.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	workers  sync.WaitGroup
+	started  bool
+	closing  bool
+	err      error
+	lifeMu   sync.Mutex
+	stopOnce sync.Once
+	done     chan struct{}
 }
 
 func New(conf *Config) *Node {
-	n := &Node{Conf: conf}
 	ident, err := id.New()
-	if err == nil {
-		n.Id = ident
+	if err != nil {
+		utils.Err.Printf("could not generate identity: %v", err)
 	}
+	return NewWithID(conf, ident)
+}
 
-	n.AddrDb = addressdb.New(true, 1000)
-	n.PeerDb = peer.NewDb(true, 200, "")
+// NewWithID returns a node with an existing identity, such as that of
+// a node restarted at a different address.
+func NewWithID(conf *Config, ident *id.ID) *Node {
+	n := &Node{Conf: conf, Id: ident, done: make(chan struct{})}
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	n.handshakes = newHandshakes()
+	n.static = newStaticPeers(conf.StaticPeers)
+	n.Group = group.New()
+	n.replies = newReplies(conf.IdempotencyWindow)
+	n.limiter = ratelimit.New(conf.PeerRate, conf.PeerBurst, conf.GlobalRate, conf.GlobalBurst)
+	n.limiter.SetHostLimit(conf.HostRate, conf.HostBurst)
+	n.gossipSem = make(chan struct{}, conf.MaxGossipDials)
+	n.outbound = dispatch.New(conf.SendWorkers, conf.SendQueueLimit)
+	n.swim = swim.New(swim.Config{
+		Interval:  conf.ProbeInterval,
+		Timeout:   conf.ProbeTimeout,
+		Indirect:  conf.IndirectProbes,
+		Suspicion: conf.SuspicionTimeout,
+	}, &prober{n: n}, n.peerAddrs)
+	var self dht.NodeID
+	if ident != nil {
+		self, _ = dht.KeyID(&ident.PrivateKey.PublicKey)
+	}
+	n.DHT = dht.New(self, &dhtClient{n: n}, conf.DHTBucketSize, conf.DHTAlpha)
+	n.DHT.SetRecordLimit(conf.DHTRecordLimit)
+
+	// Without a data directory the databases only live in memory.
+	eph := conf.DataDir == ""
+	// A database that cannot be loaded is replaced by an empty one in
+	// memory, so that the files are left alone, and Start fails.
+	adb, err := addressdb.New(eph, conf.AddrLimit, filepath.Join(conf.DataDir, "addresses.json"))
+	if err != nil {
+		n.initErr = err
+		adb, _ = addressdb.New(true, conf.AddrLimit, "")
+	}
+	pdb, err := peer.NewDb(eph, conf.PeerLimit, "", filepath.Join(conf.DataDir, "peers.json"))
+	if err != nil {
+		if n.initErr == nil {
+			n.initErr = err
+		}
+		pdb, _ = peer.NewDb(true, conf.PeerLimit, "", "")
+	}
+	n.AddrDb = adb
+	n.PeerDb = pdb
+	n.PeerDb.SetBanPolicy(conf.BanThreshold, conf.BanDuration, conf.ScoreDecay)
+	n.PeerDb.SetHostBanThreshold(conf.HostBanThreshold)
+	n.PeerDb.SetSlots(conf.InboundLimit, conf.PeerLimit)
+	if conf.Transport == nil {
+		conf.Transport = transport.TCP{}
+	}
+	dialOpts := []grpc.DialOption{grpc.WithContextDialer(conf.Transport.Dial)}
+	if conf.Faults != nil {
+		dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(
+			conf.Faults.ClientInterceptor(func() string { return n.Addr })))
+	}
+	creds, err := n.creds()
+	if err != nil && n.initErr == nil {
+		n.initErr = err
+	}
+	n.Conns = address.NewConnManager(conf.ConnLimit, conf.ConnIdleTimeout, creds, dialOpts...)
+	n.Conns.SetRetryPolicies(conf.RetryPolicies)
+	n.Conns.SetTimeouts(conf.RPCTimeout, conf.RPCTimeouts)
 
 	return n
 }
 
-func (n *Node) Start() {
-	hostname, err := os.Hostname()
-	if err != nil {
-		panic(err)
+// creds returns the transport credentials used for both accepting
+// and dialing connections, or nil when the node runs insecurely. If
+// TrustedCA cannot be decoded it returns an error, along with
+// credentials that refuse every certificate.
+func (n *Node) creds() (credentials.TransportCredentials, error) {
+	if n.Conf.Insecure {
+		return nil, nil
 	}
-	addr := fmt.Sprintf("%v:%v", hostname, n.Conf.Port)
-	n.Addr = addr
-	n.PeerDb.SetAddr(addr)
-	utils.Debug.Printf("%v started", utils.FmtAddr(n.Addr))
-	n.StartServer(addr)
+	var trusted *rsa.PublicKey
+	if n.Conf.TrustedCA != "" {
+		pk, err := utils.DecodePublicKey(n.Conf.TrustedCA)
+		if err != nil {
+			err = fmt.Errorf("could not decode trusted CA key: %v", err)
+			conf := n.Id.TLSConfig(nil)
+			conf.VerifyPeerCertificate = func([][]byte, [][]*x509.Certificate) error { return err }
+			return credentials.NewTLS(conf), err
+		}
+		trusted = pk
+	}
+	return credentials.NewTLS(n.Id.TLSConfig(trusted)), nil
 }
 
 func (n *Node) NewGroup() {
-	n.Group = group.New()
+	n.Group.Reset()
 }
 
 func (n *Node) AddAMember(addr string) {
@@ -68,30 +180,35 @@ func (n *Node) AddAMember(addr string) {
 		n.Group.AddMember(n.PeerDb.Get(addr))
 		utils.Debug.Printf("%v added member %v",
 			utils.FmtAddr(n.Addr), utils.FmtAddr(addr))
-		n.Group.GenerateNewKeys()
-		for _, p := range n.Group.Members {
-			signa, err := utils.Sign(n.Id.PrivateKey, n.Group.Key)
+		key := n.Group.GenerateNewKeys()
+		for _, p := range n.Group.Online() {
+			signa, err := utils.Sign(n.Id.PrivateKey, key)
 			//_, err := utils.Sign(n.Id.PrivateKey, n.Group.Key)
 			if err != nil {
 				utils.Err.Printf("%v received error when signing new group key",
 					utils.FmtAddr(n.Addr))
 			}
 			membies := n.Group.GetMembers()
-			membies = append(membies, n.Addr)
-			gcc := GroupChange{n.Id.Certificate, membies, n.Group.Key, signa}
+			membies = append(membies, group.Member{ID: n.Fingerprint(), Addr: n.Addr})
+			gcc := GroupChange{n.Id.Certificate(), membies, key, signa, n.Addr}
 			//gcc := GroupChange{"", n.Group.GetMembers(), n.Group.Key, ""}
 			kk, err := utils.PubEncrypt(p.PublicKey, gcc.Serialize()) // TODO: broken
 			if err != nil {
 				utils.Err.Printf("%v received error when encrypting with public key",
 					utils.FmtAddr(n.Addr))
 			}
-			go func(addr *address.Address, msg string) {
-				_, err := addr.AddMemberRPC(&proto.EncKeysMem{Encryptedstuff: msg})
+			p, msg := p, kk
+			n.send(p.Addr.Addr, func() error {
+				err := n.sendToMember(p, func(addr *address.Address) error {
+					_, err := addr.AddMemberRPC(n.ctx, n.Conns, &proto.EncKeysMem{Encryptedstuff: msg})
+					return err
+				})
 				if err != nil {
 					utils.Err.Printf("%v received error when sending add message to %v",
-						utils.FmtAddr(n.Addr), utils.FmtAddr(addr.Addr))
+						utils.FmtAddr(n.Addr), utils.FmtAddr(p.Addr.Addr))
 				}
-			}(p.Addr, kk)
+				return err
+			})
 		}
 	} else {
 		utils.Err.Printf("%v cannot register via %v without being connected to him",
@@ -101,31 +218,37 @@ func (n *Node) AddAMember(addr string) {
 
 func (n *Node) KickAMember(addr string) {
 	if n.PeerDb.In(addr) {
-		n.Group.KickMember(n.PeerDb.Get(addr))
+		kicked := n.PeerDb.Get(addr)
+		n.Group.KickMember(kicked)
 		utils.Debug.Printf("%v kicked member %v",
 			utils.FmtAddr(n.Addr), utils.FmtAddr(addr))
-		n.Group.GenerateNewKeys()
-		for _, p := range n.Group.Members {
-			signa, err := utils.Sign(n.Id.PrivateKey, n.Group.Key)
+		key := n.Group.GenerateNewKeys()
+		for _, p := range n.Group.Online() {
+			signa, err := utils.Sign(n.Id.PrivateKey, key)
 			//_, err := utils.Sign(n.Id.PrivateKey, n.Group.Key)
 			if err != nil {
 				utils.Err.Printf("%v received error when signing new group key",
 					utils.FmtAddr(n.Addr))
 			}
-			gcc := GroupChange{n.Id.Certificate, []string{addr}, n.Group.Key, signa}
+			gcc := GroupChange{n.Id.Certificate(), []group.Member{{ID: kicked.ID(), Addr: addr}}, key, signa, n.Addr}
 			//gcc := GroupChange{"", []string{addr}, n.Group.Key, ""}
 			kk, err := utils.PubEncrypt(p.PublicKey, gcc.Serialize())
 			if err != nil {
 				utils.Err.Printf("%v received error when encrypting with public key",
 					utils.FmtAddr(n.Addr))
 			}
-			go func(addr *address.Address, msg string) {
-				_, err := addr.KickMemberRPC(&proto.EncKeysMem{Encryptedstuff: msg})
+			p, msg := p, kk
+			n.send(p.Addr.Addr, func() error {
+				err := n.sendToMember(p, func(addr *address.Address) error {
+					_, err := addr.KickMemberRPC(n.ctx, n.Conns, &proto.EncKeysMem{Encryptedstuff: msg})
+					return err
+				})
 				if err != nil {
 					utils.Err.Printf("%v received error when sending kick message to %v",
-						utils.FmtAddr(n.Addr), utils.FmtAddr(addr.Addr))
+						utils.FmtAddr(n.Addr), utils.FmtAddr(p.Addr.Addr))
 				}
-			}(p.Addr, kk)
+				return err
+			})
 		}
 	} else {
 		utils.Err.Printf("%v cannot register via %v without being connected to him",
@@ -134,46 +257,61 @@ func (n *Node) KickAMember(addr string) {
 }
 
 func (n *Node) MessageMyGroup(message string) {
-	for _, p := range n.Group.Members {
-		kk := utils.SymEncrypt(n.Group.GCM, message)
-		go func(addr *address.Address, msg string) {
-			_, err := addr.GroupMessageRPC(&proto.GroupIM{Encryptedmsg: msg})
+	for _, p := range n.Group.Online() {
+		kk, err := n.Group.Encrypt(message)
+		if err != nil {
+			utils.Err.Printf("%v cannot message its group: %v",
+				utils.FmtAddr(n.Addr), err)
+			return
+		}
+		p, msg := p, kk
+		n.send(p.Addr.Addr, func() error {
+			err := n.sendToMember(p, func(addr *address.Address) error {
+				_, err := addr.GroupMessageRPC(n.ctx, n.Conns, &proto.GroupIM{Encryptedmsg: msg})
+				return err
+			})
 			if err != nil {
 				utils.Err.Printf("%v received error when sending %v to %v",
-					utils.FmtAddr(n.Addr), message, utils.FmtAddr(addr.Addr))
+					utils.FmtAddr(n.Addr), message, utils.FmtAddr(p.Addr.Addr))
 			} else {
 				utils.Debug.Printf("%v sent encrypted version of %v as %v to all",
 					utils.FmtAddr(n.Addr), message, kk)
 			}
-		}(p.Addr, kk)
+			return err
+		})
 	}
 }
 
 func (n *Node) LeaveMyGroup() {
-	n.Group.KickMyMember(n.Addr)
+	n.Group.KickMyMember(n.Fingerprint())
 	utils.Debug.Printf("%v successfully left group", utils.FmtAddr(n.Addr))
-	n.Group.GenerateNewKeys()
-	for _, p := range n.Group.Members {
-		signa, err := utils.Sign(n.Id.PrivateKey, n.Group.Key)
+	key := n.Group.GenerateNewKeys()
+	for _, p := range n.Group.Online() {
+		signa, err := utils.Sign(n.Id.PrivateKey, key)
 		//_, err := utils.Sign(n.Id.PrivateKey, n.Group.Key)
 		if err != nil {
 			utils.Err.Printf("%v received error when signing new group key",
 				utils.FmtAddr(n.Addr))
 		}
-		gcc := GroupChange{n.Id.Certificate, []string{n.Addr}, n.Group.Key, signa}
+		gcc := GroupChange{n.Id.Certificate(), []group.Member{{ID: n.Fingerprint(), Addr: n.Addr}}, key, signa, n.Addr}
 		//gcc := GroupChange{"", []string{addr}, n.Group.Key, ""}
 		kk, err := utils.PubEncrypt(p.PublicKey, gcc.Serialize())
 		if err != nil {
 			utils.Err.Printf("%v received error when encrypting with public key",
 				utils.FmtAddr(n.Addr))
 		}
-		go func(addr *address.Address, msg string) {
-			_, err := addr.KickMemberRPC(&proto.EncKeysMem{Encryptedstuff: msg})
+		p, msg := p, kk
+		n.send(p.Addr.Addr, func() error {
+			err := n.sendToMember(p, func(addr *address.Address) error {
+				_, err := addr.KickMemberRPC(n.ctx, n.Conns, &proto.EncKeysMem{Encryptedstuff: msg})
+				return err
+			})
 			if err != nil {
 				utils.Err.Printf("%v received error when sending kick message to %v",
-					utils.FmtAddr(n.Addr), utils.FmtAddr(addr.Addr))
+					utils.FmtAddr(n.Addr), utils.FmtAddr(p.Addr.Addr))
 			}
-		}(p.Addr, kk)
+			return err
+		})
 	}
 }
 
@@ -185,8 +323,9 @@ func (n *Node) RegisterWithCA(addr string) {
 			utils.Err.Printf("%v received error when trying to encode public key",
 				utils.FmtAddr(n.Addr))
 		}
-		go func(myAddr string, theirAddr *address.Address, pk string) {
-			cert, err := theirAddr.RegisterRPC(&proto.Registration{Register: pk})
+		myAddr, theirAddr, pk := n.Addr, p.Addr, encodedPK
+		n.send(theirAddr.Addr, func() error {
+			cert, err := theirAddr.RegisterRPC(n.ctx, n.Conns, &proto.Registration{Register: pk})
 			if err != nil {
 				utils.Err.Printf("%v received error when registering with CA %v",
 					utils.FmtAddr(n.Addr), utils.FmtAddr(theirAddr.Addr))
@@ -194,11 +333,22 @@ func (n *Node) RegisterWithCA(addr string) {
 				utils.Debug.Printf("%v received incorrect certificate from  %v",
 					utils.FmtAddr(myAddr), utils.FmtAddr(theirAddr.Addr))
 			} else {
-				n.Id.Certificate = cert.Cert
+				n.Id.SetCertificate(cert.Cert)
 				utils.Debug.Printf("%v received valid certificate from %v",
 					utils.FmtAddr(myAddr), utils.FmtAddr(theirAddr.Addr))
+				if len(cert.X509) == 0 {
+					return nil
+				}
+				leafPk, err := id.VerifyChain([][]byte{cert.X509, cert.CaX509}, p.PublicKey)
+				if err != nil || !leafPk.Equal(&n.Id.PrivateKey.PublicKey) {
+					utils.Debug.Printf("%v received incorrect x509 certificate from %v",
+						utils.FmtAddr(myAddr), utils.FmtAddr(theirAddr.Addr))
+					return nil
+				}
+				n.Id.SetIssuedCert(cert.X509, cert.CaX509)
 			}
-		}(n.Addr, p.Addr, encodedPK)
+			return err
+		})
 	} else {
 		utils.Err.Printf("%v cannot register via CA %v without being connected to him",
 			utils.FmtAddr(n.Addr), utils.FmtAddr(addr))
@@ -206,48 +356,77 @@ func (n *Node) RegisterWithCA(addr string) {
 }
 
 func (n *Node) ConnectToPeer(addr string) {
-	a := address.New(addr, 0)
-	key, _ := utils.EncodePublicKey(&n.Id.PrivateKey.PublicKey)
-	_, err := a.VersionRPC(&proto.VersionRequest{
-		Version: uint32(n.Conf.Version),
-		AddrYou: addr,
-		AddrMe:  n.Addr,
-		SerPk:   key,
-	})
-	if err != nil {
-		utils.Debug.Printf("%v recieved no response from VersionRPC to %v",
+	if err := n.handshake(addr); err != nil {
+		utils.Debug.Printf("%v could not complete version handshake with %v",
 			utils.FmtAddr(n.Addr), utils.FmtAddr(addr))
 	}
 }
 
 func (n *Node) BroadcastAddr() {
-	myAddr := proto.Address{Addr: n.Addr, LastSeen: uint32(time.Now().UnixNano())}
+	ann, err := address.Announce(n.Id.PrivateKey, n.Addr)
+	if err != nil {
+		utils.Err.Printf("%v received error when signing its address",
+			utils.FmtAddr(n.Addr))
+		return
+	}
+	myAddr := ann.Serialize()
 	for _, p := range n.PeerDb.List() {
-		go func(addr *address.Address) {
-			_, err := addr.SendAddressesRPC(&proto.Addresses{Addrs: []*proto.Address{&myAddr}})
+		addr := p.Addr
+		n.send(addr.Addr, func() error {
+			_, err := addr.SendAddressesRPC(n.ctx, n.Conns, &proto.Addresses{Addrs: []*proto.Address{myAddr}})
 			if err != nil {
 				utils.Debug.Printf("%v recieved no response from SendAddressesRPC to %v",
-					utils.FmtAddr(n.Addr), utils.FmtAddr(p.Addr.Addr))
+					utils.FmtAddr(n.Addr), utils.FmtAddr(addr.Addr))
 			}
-		}(p.Addr)
+			return err
+		})
 	}
 }
 
-func (n *Node) StartServer(addr string) {
-	lis, err := net.Listen("tcp4", addr)
+func (n *Node) StartServer(addr string) error {
+	lis, err := n.listen(addr)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	n.serve(lis)
+	return nil
+}
+
+func (n *Node) listen(addr string) (net.Listener, error) {
+	lis, err := n.Conf.Transport.Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	n.listenAddr = lis.Addr().String()
+	return lis, nil
+}
+
+func (n *Node) serve(lis net.Listener) {
 	// Open node to connections
-	n.Server = grpc.NewServer()
+	var opts []grpc.ServerOption
+	// Start already failed if the credentials could not be set up.
+	if creds, _ := n.creds(); creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+	if n.Conf.MaxMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(n.Conf.MaxMsgSize))
+	}
+	if n.Conf.Faults != nil {
+		opts = append(opts, grpc.ChainUnaryInterceptor(
+			n.Conf.Faults.ServerInterceptor(func() string { return n.Addr })))
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(n.banInterceptor, n.limitInterceptor, n.idempotencyInterceptor))
+	n.Server = grpc.NewServer(opts...)
 	proto.RegisterBrunoCoinServer(n.Server, n)
-	go func() {
-		err := n.Server.Serve(lis)
-		if err != nil {
-			fmt.Printf("ERROR {Node.StartServer}: error" +
-				"when trying to serve server")
+	srv := n.Server
+	n.spawn(&n.workers, func() {
+		// Serve only fails if the listener does; stopping the server,
+		// even before Serve runs, is not a failure.
+		if err := srv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			utils.Err.Printf("%v stopped serving: %v", utils.FmtAddr(n.Addr), err)
+			n.fail(err)
 		}
-	}()
+	})
 }
 
 func (n *Node) PauseNetwork() {
@@ -255,18 +434,16 @@ func (n *Node) PauseNetwork() {
 	utils.Debug.Printf("%v paused", utils.FmtAddr(n.Addr))
 }
 
-func (n *Node) ResumeNetwork() {
-	hostname, err := os.Hostname()
-	if err != nil {
-		panic(err)
+func (n *Node) ResumeNetwork() error {
+	// Rebind the port picked at Start so the advertised address stays
+	// valid even when the node was configured with port 0.
+	if err := n.StartServer(n.listenAddr); err != nil {
+		return err
 	}
-	addr := fmt.Sprintf("%v:%v", hostname, n.Conf.Port)
-	n.StartServer(addr)
+	// Static peers may have dropped the node while it was away.
+	n.static.kick("")
 	utils.Debug.Printf("%v resumed", utils.FmtAddr(n.Addr))
-}
-
-func (n *Node) Kill() {
-	n.Server.GracefulStop()
+	return nil
 }
 
 type Registration struct {
@@ -281,19 +458,26 @@ func (r *Registration) Serialize() *proto.Registration {
 
 type Certificate struct {
 	Certificate string
+	X509        []byte
+	CAX509      []byte
 }
 
 func (c *Certificate) Serialize() *proto.Certificate {
 	return &proto.Certificate{
-		Cert: c.Certificate,
+		Cert:   c.Certificate,
+		X509:   c.X509,
+		CaX509: c.CAX509,
 	}
 }
 
 type GroupChange struct {
 	Certificate string
-	Members     []string
+	Members     []group.Member
 	Key         string
 	SigOverKey  string
+	// Sender is the address of the node that signed Key, which the
+	// receiver must have as a peer.
+	Sender string
 }
 
 func (c *GroupChange) Serialize() string {
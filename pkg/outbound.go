@@ -0,0 +1,31 @@
+package pkg
+
+import (
+	"finalbruh/pkg/dispatch"
+	"finalbruh/pkg/utils"
+)
+
+// send queues f, which sends something to the node at addr, on the
+// outbound dispatcher. It blocks while addr's queue is full. If f fails
+// while the node is shutting down, the messages still queued for addr
+// are dropped rather than each retried in turn.
+func (n *Node) send(addr string, f func() error) {
+	job := func() {
+		if err := f(); err == nil || !n.shuttingDown() {
+			return
+		}
+		if k := n.outbound.Discard(addr); k > 0 {
+			utils.Debug.Printf("%v dropped %v messages to unreachable %v",
+				utils.FmtAddr(n.Addr), k, utils.FmtAddr(addr))
+		}
+	}
+	if err := n.outbound.Submit(n.ctx, addr, job); err != nil {
+		utils.Debug.Printf("%v dropped a message to %v: %v",
+			utils.FmtAddr(n.Addr), utils.FmtAddr(addr), err)
+	}
+}
+
+// OutboundStats returns the load of the queues of outbound messages.
+func (n *Node) OutboundStats() dispatch.Stats {
+	return n.outbound.Stats()
+}
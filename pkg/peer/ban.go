@@ -0,0 +1,59 @@
+package peer
+
+import (
+	"finalbruh/pkg/id"
+	"finalbruh/pkg/netaddr"
+	"time"
+)
+
+const (
+	DefaultBanThreshold = 100
+	DefaultBanDuration  = 24 * time.Hour
+	DefaultScoreDecay   = time.Minute
+	// Many nodes may share a host, so a host takes more misbehavior
+	// than a single key before it is banned.
+	DefaultHostBanThreshold = 4 * DefaultBanThreshold
+)
+
+// maxScores bounds the number of callers whose misbehavior is tracked.
+const maxScores = 10000
+
+type Ban struct {
+	ID    string
+	Until time.Time
+}
+
+// misbehavior is a caller's score as of the time it last changed.
+type misbehavior struct {
+	score   int
+	updated time.Time
+}
+
+// decayed returns the score left after one point has been forgiven for
+// every decay since it last changed, moving updated on by as many
+// decays. A decay of 0 keeps scores.
+func (m *misbehavior) decayed(decay time.Duration, now time.Time) int {
+	if decay <= 0 {
+		return m.score
+	}
+	k := int(now.Sub(m.updated) / decay)
+	if k >= m.score {
+		m.score, m.updated = 0, now
+		return 0
+	}
+	m.score -= k
+	m.updated = m.updated.Add(time.Duration(k) * decay)
+	return m.score
+}
+
+// matches reports whether p is the node identified by callerID, which
+// is either the fingerprint of its key or its host.
+func (p *Peer) matches(callerID string) bool {
+	if p.PublicKey != nil {
+		if fp, err := id.Fingerprint(p.PublicKey); err == nil && fp == callerID {
+			return true
+		}
+	}
+	hp, err := netaddr.Parse(p.Addr.Addr)
+	return err == nil && hp.Host == callerID
+}
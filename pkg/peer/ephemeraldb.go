@@ -3,37 +3,106 @@ package peer
 import (
 	"errors"
 	"math/rand"
+	"sort"
+	"sync"
+	"time"
 )
 
+// EphemeralPeerDb keeps peers in memory. It is safe for concurrent use
+// and stores and hands out copies of peers. Inbound and outbound peers
+// have separate slots.
 type EphemeralPeerDb struct {
-	peers map[string]*Peer
-	limit int
-	Addr  string
+	peers       map[string]*Peer
+	maxInbound  int
+	maxOutbound int
+	Addr        string
+
+	scores        map[string]*misbehavior
+	bans          map[string]time.Time
+	banThreshold  int
+	hostThreshold int
+	banDuration   time.Duration
+	scoreDecay    time.Duration
+
+	mu sync.Mutex
 }
 
 func (pdb *EphemeralPeerDb) In(k string) bool {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
 	_, in := pdb.peers[k]
 	return in
 }
 
 func (pdb *EphemeralPeerDb) SetAddr(addr string) {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
 	pdb.Addr = addr
 }
 
-func (pdb *EphemeralPeerDb) Add(p *Peer) bool {
-	oldP := pdb.peers[p.Addr.Addr]
-	if (oldP != nil && p.Addr.LastSeen != oldP.Addr.LastSeen) || (oldP == nil && len(pdb.peers) < pdb.limit) {
-		pdb.peers[p.Addr.Addr] = p
-		return true
+func (pdb *EphemeralPeerDb) SetSlots(inbound, outbound int) {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	pdb.maxInbound = inbound
+	pdb.maxOutbound = outbound
+}
+
+func (pdb *EphemeralPeerDb) Add(p *Peer) (*Peer, bool) {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	if oldP := pdb.peers[p.Addr.Addr]; oldP != nil {
+		if p.Addr.LastSeen == oldP.Addr.LastSeen {
+			return nil, false
+		}
+		c := p.Copy()
+		c.Since = oldP.Since
+		// A peer keeps its slot when it reconnects the other way. An
+		// inbound peer the node dials moves to an outbound slot only
+		// if one is free.
+		c.Inbound = oldP.Inbound
+		if oldP.Inbound && !p.Inbound {
+			if _, out := pdb.counts(); c.Static || out < pdb.maxOutbound {
+				c.Inbound = false
+			}
+		}
+		pdb.peers[p.Addr.Addr] = c
+		return nil, true
+	}
+	in, out := pdb.counts()
+	var evicted *Peer
+	full := (p.Inbound && in >= pdb.maxInbound) || (!p.Inbound && out >= pdb.maxOutbound)
+	if full && !p.Static {
+		if evicted = pdb.makeRoom(p); evicted == nil {
+			return nil, false
+		}
+		delete(pdb.peers, evicted.Addr.Addr)
 	}
-	return false
+	c := p.Copy()
+	if c.Since.IsZero() {
+		c.Since = time.Now()
+	}
+	pdb.peers[p.Addr.Addr] = c
+	return evicted, true
 }
 
 func (pdb *EphemeralPeerDb) Get(addr string) *Peer {
-	return pdb.peers[addr]
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	if p := pdb.peers[addr]; p != nil {
+		return p.Copy()
+	}
+	return nil
 }
 
-func (pdb *EphemeralPeerDb) UpdateLastSeen(addr string, lastSeen uint32) error {
+func (pdb *EphemeralPeerDb) Remove(addr string) {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	delete(pdb.peers, addr)
+}
+
+func (pdb *EphemeralPeerDb) UpdateLastSeen(addr string, lastSeen int64) error {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
 	p := pdb.peers[addr]
 	if p == nil {
 		return errors.New("peer not found")
@@ -44,10 +113,12 @@ func (pdb *EphemeralPeerDb) UpdateLastSeen(addr string, lastSeen uint32) error {
 
 // Get up to n random peers
 func (pdb *EphemeralPeerDb) GetRandom(n int, exclude []string) []*Peer {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
 	peers := make([]*Peer, 0)
 	if n >= len(pdb.peers) {
 		for _, peer := range pdb.peers {
-			peers = append(peers, peer)
+			peers = append(peers, peer.Copy())
 		}
 		return peers
 	}
@@ -62,17 +133,164 @@ func (pdb *EphemeralPeerDb) GetRandom(n int, exclude []string) []*Peer {
 		}
 	}
 	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	if n > len(keys) {
+		n = len(keys)
+	}
 	randKeys := keys[:n]
 	for _, key := range randKeys {
-		peers = append(peers, pdb.peers[key])
+		peers = append(peers, pdb.peers[key].Copy())
 	}
 	return peers
 }
 
 func (pdb *EphemeralPeerDb) List() []*Peer {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
 	peers := make([]*Peer, 0)
 	for _, peer := range pdb.peers {
-		peers = append(peers, peer)
+		peers = append(peers, peer.Copy())
 	}
 	return peers
 }
+
+func (pdb *EphemeralPeerDb) SetBanPolicy(threshold int, duration, decay time.Duration) {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	pdb.banThreshold = threshold
+	pdb.banDuration = duration
+	pdb.scoreDecay = decay
+}
+
+func (pdb *EphemeralPeerDb) SetHostBanThreshold(threshold int) {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	pdb.hostThreshold = threshold
+}
+
+func (pdb *EphemeralPeerDb) Misbehaving(id string, score int) bool {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	return pdb.misbehaving(id, score, pdb.banThreshold)
+}
+
+func (pdb *EphemeralPeerDb) HostMisbehaving(host string, score int) bool {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	return pdb.misbehaving(host, score, pdb.hostThreshold)
+}
+
+func (pdb *EphemeralPeerDb) misbehaving(id string, score, threshold int) bool {
+	if pdb.isBanned(id) {
+		return false
+	}
+	s := pdb.scoreOf(id) + score
+	if _, ok := pdb.scores[id]; !ok && len(pdb.scores) >= maxScores {
+		pdb.pruneScores()
+	}
+	pdb.scores[id] = &misbehavior{score: s, updated: time.Now()}
+	if threshold <= 0 || s < threshold {
+		return false
+	}
+	pdb.ban(id, time.Now().Add(pdb.banDuration))
+	return true
+}
+
+// pruneScores forgets scores that have decayed to 0 and then the
+// lowest scores, which are the least likely to lead to a ban, until a
+// tenth of the room is free.
+func (pdb *EphemeralPeerDb) pruneScores() {
+	ids := make([]string, 0, len(pdb.scores))
+	for id := range pdb.scores {
+		if pdb.scoreOf(id) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	excess := len(ids) - maxScores*9/10
+	if excess <= 0 {
+		return
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return pdb.scores[ids[i]].score < pdb.scores[ids[j]].score
+	})
+	for _, id := range ids[:excess] {
+		delete(pdb.scores, id)
+	}
+}
+
+func (pdb *EphemeralPeerDb) Score(id string) int {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	return pdb.scoreOf(id)
+}
+
+// scoreOf returns the current score of id, forgetting it once it has
+// decayed to 0.
+func (pdb *EphemeralPeerDb) scoreOf(id string) int {
+	m := pdb.scores[id]
+	if m == nil {
+		return 0
+	}
+	s := m.decayed(pdb.scoreDecay, time.Now())
+	if s == 0 {
+		delete(pdb.scores, id)
+	}
+	return s
+}
+
+// Ban refuses id until the given time and disconnects any peer it
+// identifies.
+func (pdb *EphemeralPeerDb) Ban(id string, until time.Time) {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	pdb.ban(id, until)
+}
+
+func (pdb *EphemeralPeerDb) ban(id string, until time.Time) {
+	pdb.bans[id] = until
+	delete(pdb.scores, id)
+	for addr, p := range pdb.peers {
+		if p.matches(id) {
+			delete(pdb.peers, addr)
+		}
+	}
+}
+
+func (pdb *EphemeralPeerDb) Unban(id string) {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	delete(pdb.bans, id)
+	delete(pdb.scores, id)
+}
+
+func (pdb *EphemeralPeerDb) IsBanned(id string) bool {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	return pdb.isBanned(id)
+}
+
+func (pdb *EphemeralPeerDb) isBanned(id string) bool {
+	until, ok := pdb.bans[id]
+	if ok && time.Now().After(until) {
+		delete(pdb.bans, id)
+		return false
+	}
+	return ok
+}
+
+func (pdb *EphemeralPeerDb) Bans() []Ban {
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+	bans := make([]Ban, 0, len(pdb.bans))
+	for id, until := range pdb.bans {
+		if pdb.isBanned(id) {
+			bans = append(bans, Ban{ID: id, Until: until})
+		}
+	}
+	return bans
+}
+
+func (pdb *EphemeralPeerDb) Close() error {
+	return nil
+}
+
+func (pdb *EphemeralPeerDb) Discard() {}
@@ -0,0 +1,167 @@
+package peer
+
+import (
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/store"
+	"finalbruh/pkg/utils"
+	"sync"
+	"time"
+)
+
+const flushInterval = time.Second
+
+// peerRecord is a peer as saved. Files written before Inbound and
+// Since were saved load as outbound peers added on loading.
+type peerRecord struct {
+	Addr      *address.Address
+	Version   uint32
+	PublicKey string
+	Inbound   bool
+	Since     time.Time
+}
+
+type fileState struct {
+	Peers []peerRecord
+	Bans  []Ban
+}
+
+// FilePeerDb is an EphemeralPeerDb whose peers and bans are saved to a
+// file, so a restarted node can reconnect to its peers and keeps
+// refusing the nodes it banned. Misbehavior scores are not saved.
+type FilePeerDb struct {
+	*EphemeralPeerDb
+	persist *store.Persister
+
+	// state is the latest snapshot, taken by the goroutine making a
+	// change so the persister never reads the maps concurrently.
+	// Changes are made one at a time under writeMu, so a snapshot is
+	// never replaced by an older one.
+	state   fileState
+	stateMu sync.Mutex
+	writeMu sync.Mutex
+}
+
+// OpenDb returns a peer database kept in the file at path, loading the
+// peers and bans saved there.
+func OpenDb(limit int, addr string, path string) (*FilePeerDb, error) {
+	var st fileState
+	if err := store.Load(path, &st); err != nil {
+		return nil, err
+	}
+	fdb := &FilePeerDb{EphemeralPeerDb: newEphemeralDb(limit, addr)}
+	for _, b := range st.Bans {
+		if time.Now().Before(b.Until) {
+			fdb.EphemeralPeerDb.Ban(b.ID, b.Until)
+		}
+	}
+	now := time.Now()
+	for _, r := range st.Peers {
+		if r.Addr == nil {
+			continue
+		}
+		pk, err := utils.DecodePublicKey(r.PublicKey)
+		if err != nil {
+			continue
+		}
+		p := New(r.Addr, r.Version, pk)
+		p.Inbound, p.Since = r.Inbound, r.Since
+		if p.Since.IsZero() {
+			p.Since = now
+		}
+		// The peers are restored as saved rather than competing for
+		// slots, which are only configured afterwards.
+		fdb.peers[r.Addr.Addr] = p
+	}
+	fdb.snapshot()
+	fdb.persist = store.NewPersister(path, flushInterval, func() interface{} {
+		fdb.stateMu.Lock()
+		defer fdb.stateMu.Unlock()
+		return fdb.state
+	})
+	return fdb, nil
+}
+
+func (fdb *FilePeerDb) snapshot() {
+	var st fileState
+	for _, p := range fdb.EphemeralPeerDb.List() {
+		pk, err := utils.EncodePublicKey(p.PublicKey)
+		if err != nil {
+			continue
+		}
+		st.Peers = append(st.Peers, peerRecord{
+			Addr:      p.Addr,
+			Version:   p.Version,
+			PublicKey: pk,
+			Inbound:   p.Inbound,
+			Since:     p.Since,
+		})
+	}
+	st.Bans = fdb.EphemeralPeerDb.Bans()
+	fdb.stateMu.Lock()
+	fdb.state = st
+	fdb.stateMu.Unlock()
+	if fdb.persist != nil {
+		fdb.persist.Dirty()
+	}
+}
+
+func (fdb *FilePeerDb) Add(p *Peer) (*Peer, bool) {
+	fdb.writeMu.Lock()
+	defer fdb.writeMu.Unlock()
+	evicted, ok := fdb.EphemeralPeerDb.Add(p)
+	if ok {
+		fdb.snapshot()
+	}
+	return evicted, ok
+}
+
+func (fdb *FilePeerDb) Remove(addr string) {
+	fdb.writeMu.Lock()
+	defer fdb.writeMu.Unlock()
+	fdb.EphemeralPeerDb.Remove(addr)
+	fdb.snapshot()
+}
+
+func (fdb *FilePeerDb) Misbehaving(id string, score int) bool {
+	fdb.writeMu.Lock()
+	defer fdb.writeMu.Unlock()
+	if !fdb.EphemeralPeerDb.Misbehaving(id, score) {
+		return false
+	}
+	fdb.snapshot()
+	return true
+}
+
+func (fdb *FilePeerDb) HostMisbehaving(host string, score int) bool {
+	fdb.writeMu.Lock()
+	defer fdb.writeMu.Unlock()
+	if !fdb.EphemeralPeerDb.HostMisbehaving(host, score) {
+		return false
+	}
+	fdb.snapshot()
+	return true
+}
+
+func (fdb *FilePeerDb) Ban(id string, until time.Time) {
+	fdb.writeMu.Lock()
+	defer fdb.writeMu.Unlock()
+	fdb.EphemeralPeerDb.Ban(id, until)
+	fdb.snapshot()
+}
+
+func (fdb *FilePeerDb) Unban(id string) {
+	fdb.writeMu.Lock()
+	defer fdb.writeMu.Unlock()
+	fdb.EphemeralPeerDb.Unban(id)
+	fdb.snapshot()
+}
+
+// Close writes the file a last time.
+func (fdb *FilePeerDb) Close() error {
+	return fdb.persist.Close()
+}
+
+// Discard stops without writing the file.
+func (fdb *FilePeerDb) Discard() {
+	fdb.persist.Discard()
+}
@@ -3,14 +3,46 @@ package peer
 import (
 	"crypto/rsa"
 	"finalbruh/pkg/address"
+	"finalbruh/pkg/id"
+	"time"
 )
 
 type Peer struct {
 	Addr      *address.Address
 	Version   uint32
 	PublicKey *rsa.PublicKey
+
+	// Inbound is set on peers that connected to the node, as opposed
+	// to peers the node dialed. Since is when the peer was added.
+	Inbound bool
+	Since   time.Time
+	// Static is set on peers the node is configured to keep. They take
+	// no slot and are never evicted to make room for another peer.
+	Static bool
 }
 
 func New(addr *address.Address, version uint32, pk *rsa.PublicKey) *Peer {
 	return &Peer{Addr: addr, Version: version, PublicKey: pk}
 }
+
+// Copy returns a copy of p whose address record can be changed
+// without affecting p.
+func (p *Peer) Copy() *Peer {
+	c := *p
+	if p.Addr != nil {
+		c.Addr = p.Addr.Copy()
+	}
+	return &c
+}
+
+// ID returns the fingerprint of the peer's key, or "" if it has none.
+func (p *Peer) ID() string {
+	if p.PublicKey == nil {
+		return ""
+	}
+	fp, err := id.Fingerprint(p.PublicKey)
+	if err != nil {
+		return ""
+	}
+	return fp
+}
@@ -1,15 +1,63 @@
 package peer
 
+import "time"
+
 type PeerDb interface {
-	Add(*Peer) bool
+	// Add stores a peer, or updates it if its address is known. When
+	// the peer's inbound or outbound slots are full it replaces a worse
+	// peer, which it returns, or is refused.
+	Add(*Peer) (evicted *Peer, added bool)
 	Get(string) *Peer
-	UpdateLastSeen(string, uint32) error
+	Remove(string)
+	UpdateLastSeen(string, int64) error
 	List() []*Peer
 	GetRandom(int, []string) []*Peer
 	In(string) bool
 	SetAddr(string)
+	SetSlots(inbound, outbound int)
+
+	// Misbehavior is tracked per caller ID, which is a key fingerprint
+	// or a host. Misbehaving adds to the ID's score and bans it once
+	// the score reaches the threshold, reporting whether it did. A
+	// score drops by one point every decay. HostMisbehaving is
+	// Misbehaving for a caller's host, which is banned at the host
+	// threshold instead.
+	SetBanPolicy(threshold int, duration, decay time.Duration)
+	SetHostBanThreshold(threshold int)
+	Misbehaving(id string, score int) bool
+	HostMisbehaving(host string, score int) bool
+	Score(id string) int
+	Ban(id string, until time.Time)
+	Unban(id string)
+	IsBanned(id string) bool
+	Bans() []Ban
+
+	Close() error
+	// Discard releases the database without saving it.
+	Discard()
+}
+
+// NewDb returns a peer database for the node at addr with up to limit
+// inbound and limit outbound peers. Unless it is ephemeral it is kept
+// in the file at path.
+func NewDb(eph bool, limit int, addr string, path string) (PeerDb, error) {
+	if eph {
+		return newEphemeralDb(limit, addr), nil
+	}
+	return OpenDb(limit, addr, path)
 }
 
-func NewDb(eph bool, limit int, addr string) PeerDb {
-	return &EphemeralPeerDb{peers: make(map[string]*Peer), limit: limit, Addr: addr}
+func newEphemeralDb(limit int, addr string) *EphemeralPeerDb {
+	return &EphemeralPeerDb{
+		peers:         make(map[string]*Peer),
+		maxInbound:    limit,
+		maxOutbound:   limit,
+		Addr:          addr,
+		scores:        make(map[string]*misbehavior),
+		bans:          make(map[string]time.Time),
+		banThreshold:  DefaultBanThreshold,
+		hostThreshold: DefaultHostBanThreshold,
+		banDuration:   DefaultBanDuration,
+		scoreDecay:    DefaultScoreDecay,
+	}
 }
@@ -0,0 +1,115 @@
+package peer
+
+import (
+	"finalbruh/pkg/id"
+	"finalbruh/pkg/netaddr"
+)
+
+// score returns the misbehavior score of p, counting both its key and
+// its host.
+func (pdb *EphemeralPeerDb) score(p *Peer) int {
+	s := 0
+	if p.PublicKey != nil {
+		if fp, err := id.Fingerprint(p.PublicKey); err == nil {
+			s += pdb.scoreOf(fp)
+		}
+	}
+	if hp, err := netaddr.Parse(p.Addr.Addr); err == nil {
+		s += pdb.scoreOf(hp.Host)
+	}
+	return s
+}
+
+func group(p *Peer) string {
+	hp, err := netaddr.Parse(p.Addr.Addr)
+	if err != nil {
+		return p.Addr.Addr
+	}
+	return hp.Group()
+}
+
+func (pdb *EphemeralPeerDb) counts() (inbound, outbound int) {
+	for _, p := range pdb.peers {
+		if p.Static {
+			continue
+		}
+		if p.Inbound {
+			inbound++
+		} else {
+			outbound++
+		}
+	}
+	return inbound, outbound
+}
+
+// worstInbound returns the inbound peer to evict first: the one with
+// the highest misbehavior score, then the one whose network group has
+// the most inbound peers, then the one that connected last. crowd is
+// set to the inbound peers per group.
+func (pdb *EphemeralPeerDb) worstInbound() (worst *Peer, crowd map[string]int) {
+	crowd = make(map[string]int)
+	for _, p := range pdb.peers {
+		if p.Inbound && !p.Static {
+			crowd[group(p)]++
+		}
+	}
+	worstScore := 0
+	for _, p := range pdb.peers {
+		if !p.Inbound || p.Static {
+			continue
+		}
+		s := pdb.score(p)
+		switch {
+		case worst == nil || s > worstScore:
+		case s < worstScore:
+			continue
+		case crowd[group(p)] > crowd[group(worst)]:
+		case crowd[group(p)] < crowd[group(worst)]:
+			continue
+		case p.Since.After(worst.Since):
+		default:
+			continue
+		}
+		worst, worstScore = p, s
+	}
+	return worst, crowd
+}
+
+// worstOutbound returns the misbehaving outbound peer with the highest
+// score, or nil if every outbound peer behaves. Outbound peers in good
+// standing are never displaced.
+func (pdb *EphemeralPeerDb) worstOutbound() *Peer {
+	var worst *Peer
+	worstScore := 0
+	for _, p := range pdb.peers {
+		if p.Inbound || p.Static {
+			continue
+		}
+		if s := pdb.score(p); s > worstScore {
+			worst, worstScore = p, s
+		}
+	}
+	return worst
+}
+
+// makeRoom finds the peer p should replace when its slots are full, or
+// returns nil if p is no better than any of them. An inbound peer
+// replaces the worst inbound peer if it misbehaved less, or as much but
+// comes from a network group with fewer inbound peers.
+func (pdb *EphemeralPeerDb) makeRoom(p *Peer) *Peer {
+	if !p.Inbound {
+		if worst := pdb.worstOutbound(); worst != nil && pdb.score(p) < pdb.score(worst) {
+			return worst
+		}
+		return nil
+	}
+	worst, crowd := pdb.worstInbound()
+	if worst == nil {
+		return nil
+	}
+	s, ws := pdb.score(p), pdb.score(worst)
+	if s < ws || (s == ws && crowd[group(p)]+1 < crowd[group(worst)]) {
+		return worst
+	}
+	return nil
+}
@@ -67,10 +67,12 @@ type VersionRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`               // a constant that defines the bitcoin P2P protocol version the client “speaks”
-	AddrYou string `protobuf:"bytes,2,opt,name=addr_you,json=addrYou,proto3" json:"addr_you,omitempty"` // the IP address of the remote node as seen from this node
-	AddrMe  string `protobuf:"bytes,3,opt,name=addr_me,json=addrMe,proto3" json:"addr_me,omitempty"`    // the IP address of the local node, as discovered by the local node
-	SerPk   string `protobuf:"bytes,4,opt,name=ser_pk,json=serPk,proto3" json:"ser_pk,omitempty"`
+	Version      uint32   `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`               // a constant that defines the bitcoin P2P protocol version the client “speaks”
+	AddrYou      string   `protobuf:"bytes,2,opt,name=addr_you,json=addrYou,proto3" json:"addr_you,omitempty"` // the IP address of the remote node as seen from this node
+	AddrMe       string   `protobuf:"bytes,3,opt,name=addr_me,json=addrMe,proto3" json:"addr_me,omitempty"`    // the IP address of the local node, as discovered by the local node
+	SerPk        string   `protobuf:"bytes,4,opt,name=ser_pk,json=serPk,proto3" json:"ser_pk,omitempty"`
+	Nonce        []byte   `protobuf:"bytes,5,opt,name=nonce,proto3" json:"nonce,omitempty"`               // fresh challenge the receiver must sign
+	Announcement *Address `protobuf:"bytes,6,opt,name=announcement,proto3" json:"announcement,omitempty"` // the sender's signed address record
 }
 
 func (x *VersionRequest) Reset() {
@@ -133,19 +135,265 @@ func (x *VersionRequest) GetSerPk() string {
 	return ""
 }
 
+func (x *VersionRequest) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *VersionRequest) GetAnnouncement() *Address {
+	if x != nil {
+		return x.Announcement
+	}
+	return nil
+}
+
+type VersionReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SerPk        string   `protobuf:"bytes,1,opt,name=ser_pk,json=serPk,proto3" json:"ser_pk,omitempty"`  // the receiver's public key
+	Nonce        []byte   `protobuf:"bytes,2,opt,name=nonce,proto3" json:"nonce,omitempty"`               // fresh challenge the initiator must sign in VerAck
+	Sig          string   `protobuf:"bytes,3,opt,name=sig,proto3" json:"sig,omitempty"`                   // the receiver's signature over the initiator's nonce
+	Announcement *Address `protobuf:"bytes,4,opt,name=announcement,proto3" json:"announcement,omitempty"` // the receiver's signed address record
+}
+
+func (x *VersionReply) Reset() {
+	*x = VersionReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VersionReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionReply) ProtoMessage() {}
+
+func (x *VersionReply) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionReply.ProtoReflect.Descriptor instead.
+func (*VersionReply) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *VersionReply) GetSerPk() string {
+	if x != nil {
+		return x.SerPk
+	}
+	return ""
+}
+
+func (x *VersionReply) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *VersionReply) GetSig() string {
+	if x != nil {
+		return x.Sig
+	}
+	return ""
+}
+
+func (x *VersionReply) GetAnnouncement() *Address {
+	if x != nil {
+		return x.Announcement
+	}
+	return nil
+}
+
+type VersionAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	AddrMe string `protobuf:"bytes,1,opt,name=addr_me,json=addrMe,proto3" json:"addr_me,omitempty"` // the initiator's address, as sent in its VersionRequest
+	Sig    string `protobuf:"bytes,2,opt,name=sig,proto3" json:"sig,omitempty"`                     // the initiator's signature over the receiver's nonce
+}
+
+func (x *VersionAck) Reset() {
+	*x = VersionAck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VersionAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionAck) ProtoMessage() {}
+
+func (x *VersionAck) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionAck.ProtoReflect.Descriptor instead.
+func (*VersionAck) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *VersionAck) GetAddrMe() string {
+	if x != nil {
+		return x.AddrMe
+	}
+	return ""
+}
+
+func (x *VersionAck) GetSig() string {
+	if x != nil {
+		return x.Sig
+	}
+	return ""
+}
+
+type DialBackRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	AddrMe string `protobuf:"bytes,1,opt,name=addr_me,json=addrMe,proto3" json:"addr_me,omitempty"` // the address of the receiver of the initiator's Version
+	Nonce  []byte `protobuf:"bytes,2,opt,name=nonce,proto3" json:"nonce,omitempty"`                 // fresh challenge the initiator must sign
+}
+
+func (x *DialBackRequest) Reset() {
+	*x = DialBackRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DialBackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DialBackRequest) ProtoMessage() {}
+
+func (x *DialBackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DialBackRequest.ProtoReflect.Descriptor instead.
+func (*DialBackRequest) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DialBackRequest) GetAddrMe() string {
+	if x != nil {
+		return x.AddrMe
+	}
+	return ""
+}
+
+func (x *DialBackRequest) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+type DialBackReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sig string `protobuf:"bytes,1,opt,name=sig,proto3" json:"sig,omitempty"` // the initiator's signature over the nonce
+}
+
+func (x *DialBackReply) Reset() {
+	*x = DialBackReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DialBackReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DialBackReply) ProtoMessage() {}
+
+func (x *DialBackReply) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DialBackReply.ProtoReflect.Descriptor instead.
+func (*DialBackReply) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DialBackReply) GetSig() string {
+	if x != nil {
+		return x.Sig
+	}
+	return ""
+}
+
 type Address struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Addr     string `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`                          // actual address
-	LastSeen uint32 `protobuf:"varint,2,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"` // A unix timestamp or block number (pg 114)
+	Addr       string `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`                                  // actual address
+	LastSeen   uint32 `protobuf:"varint,2,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`         // legacy: unix seconds, read only if last_seen_ns is unset
+	SerPk      string `protobuf:"bytes,3,opt,name=ser_pk,json=serPk,proto3" json:"ser_pk,omitempty"`                   // key of the node at addr
+	Timestamp  int64  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                       // unix nanoseconds at which the node announced addr
+	Sig        string `protobuf:"bytes,5,opt,name=sig,proto3" json:"sig,omitempty"`                                    // the node's signature over addr, its key and timestamp
+	LastSeenNs int64  `protobuf:"varint,6,opt,name=last_seen_ns,json=lastSeenNs,proto3" json:"last_seen_ns,omitempty"` // unix nanoseconds at which addr was last seen
 }
 
 func (x *Address) Reset() {
 	*x = Address{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_broseph_proto_msgTypes[2]
+		mi := &file_broseph_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -158,7 +406,7 @@ func (x *Address) String() string {
 func (*Address) ProtoMessage() {}
 
 func (x *Address) ProtoReflect() protoreflect.Message {
-	mi := &file_broseph_proto_msgTypes[2]
+	mi := &file_broseph_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -171,7 +419,7 @@ func (x *Address) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Address.ProtoReflect.Descriptor instead.
 func (*Address) Descriptor() ([]byte, []int) {
-	return file_broseph_proto_rawDescGZIP(), []int{2}
+	return file_broseph_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *Address) GetAddr() string {
@@ -188,6 +436,34 @@ func (x *Address) GetLastSeen() uint32 {
 	return 0
 }
 
+func (x *Address) GetSerPk() string {
+	if x != nil {
+		return x.SerPk
+	}
+	return ""
+}
+
+func (x *Address) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Address) GetSig() string {
+	if x != nil {
+		return x.Sig
+	}
+	return ""
+}
+
+func (x *Address) GetLastSeenNs() int64 {
+	if x != nil {
+		return x.LastSeenNs
+	}
+	return 0
+}
+
 type Addresses struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -199,7 +475,7 @@ type Addresses struct {
 func (x *Addresses) Reset() {
 	*x = Addresses{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_broseph_proto_msgTypes[3]
+		mi := &file_broseph_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -212,7 +488,7 @@ func (x *Addresses) String() string {
 func (*Addresses) ProtoMessage() {}
 
 func (x *Addresses) ProtoReflect() protoreflect.Message {
-	mi := &file_broseph_proto_msgTypes[3]
+	mi := &file_broseph_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -225,41 +501,702 @@ func (x *Addresses) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Addresses.ProtoReflect.Descriptor instead.
 func (*Addresses) Descriptor() ([]byte, []int) {
-	return file_broseph_proto_rawDescGZIP(), []int{3}
+	return file_broseph_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Addresses) GetAddrs() []*Address {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+type GetAddressesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Limit     uint32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`                          // most addresses wanted; 0 leaves it to the node
+	SeenAfter int64  `protobuf:"varint,2,opt,name=seen_after,json=seenAfter,proto3" json:"seen_after,omitempty"` // only addresses last seen after this, in unix nanoseconds
+}
+
+func (x *GetAddressesRequest) Reset() {
+	*x = GetAddressesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetAddressesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAddressesRequest) ProtoMessage() {}
+
+func (x *GetAddressesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAddressesRequest.ProtoReflect.Descriptor instead.
+func (*GetAddressesRequest) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetAddressesRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetAddressesRequest) GetSeenAfter() int64 {
+	if x != nil {
+		return x.SeenAfter
+	}
+	return 0
+}
+
+type ListAddressesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cursor string `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"` // next_cursor of the previous page, empty for the first
+	Limit  uint32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`  // page size; 0 leaves it to the node
+}
+
+func (x *ListAddressesRequest) Reset() {
+	*x = ListAddressesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListAddressesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAddressesRequest) ProtoMessage() {}
+
+func (x *ListAddressesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAddressesRequest.ProtoReflect.Descriptor instead.
+func (*ListAddressesRequest) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListAddressesRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListAddressesRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type AddressPage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Addrs      []*Address `protobuf:"bytes,1,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	NextCursor string     `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // empty on the last page
+}
+
+func (x *AddressPage) Reset() {
+	*x = AddressPage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AddressPage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddressPage) ProtoMessage() {}
+
+func (x *AddressPage) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddressPage.ProtoReflect.Descriptor instead.
+func (*AddressPage) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AddressPage) GetAddrs() []*Address {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *AddressPage) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type Registration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Register string `protobuf:"bytes,1,opt,name=register,proto3" json:"register,omitempty"`
+}
+
+func (x *Registration) Reset() {
+	*x = Registration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Registration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Registration) ProtoMessage() {}
+
+func (x *Registration) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Registration.ProtoReflect.Descriptor instead.
+func (*Registration) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Registration) GetRegister() string {
+	if x != nil {
+		return x.Register
+	}
+	return ""
+}
+
+type Certificate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cert   string `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
+	X509   []byte `protobuf:"bytes,2,opt,name=x509,proto3" json:"x509,omitempty"`                   // DER certificate for the registrant's key, issued by the CA
+	CaX509 []byte `protobuf:"bytes,3,opt,name=ca_x509,json=caX509,proto3" json:"ca_x509,omitempty"` // DER certificate of the issuing CA
+}
+
+func (x *Certificate) Reset() {
+	*x = Certificate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Certificate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Certificate) ProtoMessage() {}
+
+func (x *Certificate) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Certificate.ProtoReflect.Descriptor instead.
+func (*Certificate) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Certificate) GetCert() string {
+	if x != nil {
+		return x.Cert
+	}
+	return ""
+}
+
+func (x *Certificate) GetX509() []byte {
+	if x != nil {
+		return x.X509
+	}
+	return nil
+}
+
+func (x *Certificate) GetCaX509() []byte {
+	if x != nil {
+		return x.CaX509
+	}
+	return nil
+}
+
+type EncKeysMem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Encryptedstuff string `protobuf:"bytes,1,opt,name=encryptedstuff,proto3" json:"encryptedstuff,omitempty"`
+}
+
+func (x *EncKeysMem) Reset() {
+	*x = EncKeysMem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EncKeysMem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncKeysMem) ProtoMessage() {}
+
+func (x *EncKeysMem) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncKeysMem.ProtoReflect.Descriptor instead.
+func (*EncKeysMem) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *EncKeysMem) GetEncryptedstuff() string {
+	if x != nil {
+		return x.Encryptedstuff
+	}
+	return ""
+}
+
+type GroupIM struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Encryptedmsg string `protobuf:"bytes,1,opt,name=encryptedmsg,proto3" json:"encryptedmsg,omitempty"`
+}
+
+func (x *GroupIM) Reset() {
+	*x = GroupIM{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GroupIM) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupIM) ProtoMessage() {}
+
+func (x *GroupIM) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupIM.ProtoReflect.Descriptor instead.
+func (*GroupIM) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GroupIM) GetEncryptedmsg() string {
+	if x != nil {
+		return x.Encryptedmsg
+	}
+	return ""
+}
+
+type PingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	AddrMe string `protobuf:"bytes,1,opt,name=addr_me,json=addrMe,proto3" json:"addr_me,omitempty"` // the address of the probing node
+	Seq    uint64 `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`                    // echoed in the Ack
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PingRequest) GetAddrMe() string {
+	if x != nil {
+		return x.AddrMe
+	}
+	return ""
+}
+
+func (x *PingRequest) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type PingReqRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	AddrMe string `protobuf:"bytes,1,opt,name=addr_me,json=addrMe,proto3" json:"addr_me,omitempty"` // the address of the probing node
+	Target string `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`               // the node the receiver should ping on the prober's behalf
+	Seq    uint64 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`                    // echoed in the Ack
+}
+
+func (x *PingReqRequest) Reset() {
+	*x = PingReqRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PingReqRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingReqRequest) ProtoMessage() {}
+
+func (x *PingReqRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingReqRequest.ProtoReflect.Descriptor instead.
+func (*PingReqRequest) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PingReqRequest) GetAddrMe() string {
+	if x != nil {
+		return x.AddrMe
+	}
+	return ""
+}
+
+func (x *PingReqRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PingReqRequest) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type Ack struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seq uint64 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Ack) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type Contact struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   []byte `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // SHA-256 fingerprint of the node's public key
+	Addr string `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+}
+
+func (x *Contact) Reset() {
+	*x = Contact{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Contact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contact) ProtoMessage() {}
+
+func (x *Contact) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contact.ProtoReflect.Descriptor instead.
+func (*Contact) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Contact) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Contact) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+type DhtRecord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SerPk     string   `protobuf:"bytes,1,opt,name=ser_pk,json=serPk,proto3" json:"ser_pk,omitempty"` // the public key the record describes
+	Addrs     []string `protobuf:"bytes,2,rep,name=addrs,proto3" json:"addrs,omitempty"`              // addresses the key's owner can be reached at
+	Timestamp int64    `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`     // unix nanoseconds; newer records replace older ones
+	Sig       string   `protobuf:"bytes,4,opt,name=sig,proto3" json:"sig,omitempty"`                  // the owner's signature over the other fields
+}
+
+func (x *DhtRecord) Reset() {
+	*x = DhtRecord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_broseph_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DhtRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DhtRecord) ProtoMessage() {}
+
+func (x *DhtRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DhtRecord.ProtoReflect.Descriptor instead.
+func (*DhtRecord) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DhtRecord) GetSerPk() string {
+	if x != nil {
+		return x.SerPk
+	}
+	return ""
+}
+
+func (x *DhtRecord) GetAddrs() []string {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *DhtRecord) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
 }
 
-func (x *Addresses) GetAddrs() []*Address {
+func (x *DhtRecord) GetSig() string {
 	if x != nil {
-		return x.Addrs
+		return x.Sig
 	}
-	return nil
+	return ""
 }
 
-type Registration struct {
+type FindRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Register string `protobuf:"bytes,1,opt,name=register,proto3" json:"register,omitempty"`
+	Sender *Contact `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
+	Target []byte   `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
 }
 
-func (x *Registration) Reset() {
-	*x = Registration{}
+func (x *FindRequest) Reset() {
+	*x = FindRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_broseph_proto_msgTypes[4]
+		mi := &file_broseph_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Registration) String() string {
+func (x *FindRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Registration) ProtoMessage() {}
+func (*FindRequest) ProtoMessage() {}
 
-func (x *Registration) ProtoReflect() protoreflect.Message {
-	mi := &file_broseph_proto_msgTypes[4]
+func (x *FindRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -270,43 +1207,50 @@ func (x *Registration) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Registration.ProtoReflect.Descriptor instead.
-func (*Registration) Descriptor() ([]byte, []int) {
-	return file_broseph_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use FindRequest.ProtoReflect.Descriptor instead.
+func (*FindRequest) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *Registration) GetRegister() string {
+func (x *FindRequest) GetSender() *Contact {
 	if x != nil {
-		return x.Register
+		return x.Sender
 	}
-	return ""
+	return nil
 }
 
-type Certificate struct {
+func (x *FindRequest) GetTarget() []byte {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+type FindNodeReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Cert string `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
+	Contacts []*Contact `protobuf:"bytes,1,rep,name=contacts,proto3" json:"contacts,omitempty"`
 }
 
-func (x *Certificate) Reset() {
-	*x = Certificate{}
+func (x *FindNodeReply) Reset() {
+	*x = FindNodeReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_broseph_proto_msgTypes[5]
+		mi := &file_broseph_proto_msgTypes[21]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Certificate) String() string {
+func (x *FindNodeReply) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Certificate) ProtoMessage() {}
+func (*FindNodeReply) ProtoMessage() {}
 
-func (x *Certificate) ProtoReflect() protoreflect.Message {
-	mi := &file_broseph_proto_msgTypes[5]
+func (x *FindNodeReply) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[21]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -317,43 +1261,44 @@ func (x *Certificate) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Certificate.ProtoReflect.Descriptor instead.
-func (*Certificate) Descriptor() ([]byte, []int) {
-	return file_broseph_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use FindNodeReply.ProtoReflect.Descriptor instead.
+func (*FindNodeReply) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{21}
 }
 
-func (x *Certificate) GetCert() string {
+func (x *FindNodeReply) GetContacts() []*Contact {
 	if x != nil {
-		return x.Cert
+		return x.Contacts
 	}
-	return ""
+	return nil
 }
 
-type EncKeysMem struct {
+type FindValueReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Encryptedstuff string `protobuf:"bytes,1,opt,name=encryptedstuff,proto3" json:"encryptedstuff,omitempty"`
+	Record   *DhtRecord `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`     // set if the receiver holds the record
+	Contacts []*Contact `protobuf:"bytes,2,rep,name=contacts,proto3" json:"contacts,omitempty"` // otherwise the closest contacts it knows
 }
 
-func (x *EncKeysMem) Reset() {
-	*x = EncKeysMem{}
+func (x *FindValueReply) Reset() {
+	*x = FindValueReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_broseph_proto_msgTypes[6]
+		mi := &file_broseph_proto_msgTypes[22]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *EncKeysMem) String() string {
+func (x *FindValueReply) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*EncKeysMem) ProtoMessage() {}
+func (*FindValueReply) ProtoMessage() {}
 
-func (x *EncKeysMem) ProtoReflect() protoreflect.Message {
-	mi := &file_broseph_proto_msgTypes[6]
+func (x *FindValueReply) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[22]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -364,43 +1309,51 @@ func (x *EncKeysMem) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use EncKeysMem.ProtoReflect.Descriptor instead.
-func (*EncKeysMem) Descriptor() ([]byte, []int) {
-	return file_broseph_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use FindValueReply.ProtoReflect.Descriptor instead.
+func (*FindValueReply) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{22}
 }
 
-func (x *EncKeysMem) GetEncryptedstuff() string {
+func (x *FindValueReply) GetRecord() *DhtRecord {
 	if x != nil {
-		return x.Encryptedstuff
+		return x.Record
 	}
-	return ""
+	return nil
 }
 
-type GroupIM struct {
+func (x *FindValueReply) GetContacts() []*Contact {
+	if x != nil {
+		return x.Contacts
+	}
+	return nil
+}
+
+type StoreRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Encryptedmsg string `protobuf:"bytes,1,opt,name=encryptedmsg,proto3" json:"encryptedmsg,omitempty"`
+	Sender *Contact   `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
+	Record *DhtRecord `protobuf:"bytes,2,opt,name=record,proto3" json:"record,omitempty"`
 }
 
-func (x *GroupIM) Reset() {
-	*x = GroupIM{}
+func (x *StoreRequest) Reset() {
+	*x = StoreRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_broseph_proto_msgTypes[7]
+		mi := &file_broseph_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GroupIM) String() string {
+func (x *StoreRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GroupIM) ProtoMessage() {}
+func (*StoreRequest) ProtoMessage() {}
 
-func (x *GroupIM) ProtoReflect() protoreflect.Message {
-	mi := &file_broseph_proto_msgTypes[7]
+func (x *StoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_broseph_proto_msgTypes[23]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -411,67 +1364,179 @@ func (x *GroupIM) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GroupIM.ProtoReflect.Descriptor instead.
-func (*GroupIM) Descriptor() ([]byte, []int) {
-	return file_broseph_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use StoreRequest.ProtoReflect.Descriptor instead.
+func (*StoreRequest) Descriptor() ([]byte, []int) {
+	return file_broseph_proto_rawDescGZIP(), []int{23}
 }
 
-func (x *GroupIM) GetEncryptedmsg() string {
+func (x *StoreRequest) GetSender() *Contact {
 	if x != nil {
-		return x.Encryptedmsg
+		return x.Sender
 	}
-	return ""
+	return nil
+}
+
+func (x *StoreRequest) GetRecord() *DhtRecord {
+	if x != nil {
+		return x.Record
+	}
+	return nil
 }
 
 var File_broseph_proto protoreflect.FileDescriptor
 
 var file_broseph_proto_rawDesc = []byte{
 	0x0a, 0x0d, 0x62, 0x72, 0x6f, 0x73, 0x65, 0x70, 0x68, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22,
-	0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x75, 0x0a, 0x0e, 0x56, 0x65, 0x72, 0x73,
-	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65,
-	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72,
-	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x19, 0x0a, 0x08, 0x61, 0x64, 0x64, 0x72, 0x5f, 0x79, 0x6f, 0x75,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x59, 0x6f, 0x75, 0x12,
-	0x17, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x5f, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x06, 0x61, 0x64, 0x64, 0x72, 0x4d, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x73, 0x65, 0x72, 0x5f,
-	0x70, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x65, 0x72, 0x50, 0x6b, 0x22,
-	0x3a, 0x0a, 0x07, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x64,
-	0x64, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x64, 0x72, 0x12, 0x1b,
-	0x0a, 0x09, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0d, 0x52, 0x08, 0x6c, 0x61, 0x73, 0x74, 0x53, 0x65, 0x65, 0x6e, 0x22, 0x2b, 0x0a, 0x09, 0x41,
-	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x12, 0x1e, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x72,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73,
-	0x73, 0x52, 0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x22, 0x2a, 0x0a, 0x0c, 0x52, 0x65, 0x67, 0x69,
-	0x73, 0x74, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x67, 0x69,
-	0x73, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x67, 0x69,
-	0x73, 0x74, 0x65, 0x72, 0x22, 0x21, 0x0a, 0x0b, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63,
-	0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x65, 0x72, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x04, 0x63, 0x65, 0x72, 0x74, 0x22, 0x34, 0x0a, 0x0a, 0x45, 0x6e, 0x63, 0x4b, 0x65,
-	0x79, 0x73, 0x4d, 0x65, 0x6d, 0x12, 0x26, 0x0a, 0x0e, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74,
-	0x65, 0x64, 0x73, 0x74, 0x75, 0x66, 0x66, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x65,
-	0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x73, 0x74, 0x75, 0x66, 0x66, 0x22, 0x2d, 0x0a,
-	0x07, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x4d, 0x12, 0x22, 0x0a, 0x0c, 0x65, 0x6e, 0x63, 0x72,
-	0x79, 0x70, 0x74, 0x65, 0x64, 0x6d, 0x73, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c,
-	0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x6d, 0x73, 0x67, 0x32, 0x88, 0x02, 0x0a,
-	0x09, 0x42, 0x72, 0x75, 0x6e, 0x6f, 0x43, 0x6f, 0x69, 0x6e, 0x12, 0x22, 0x0a, 0x07, 0x56, 0x65,
-	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0f, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x23,
+	0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0xb9, 0x01, 0x0a, 0x0e, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x19, 0x0a, 0x08, 0x61, 0x64, 0x64, 0x72, 0x5f, 0x79, 0x6f,
+	0x75, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x59, 0x6f, 0x75,
+	0x12, 0x17, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x5f, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x61, 0x64, 0x64, 0x72, 0x4d, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x73, 0x65, 0x72,
+	0x5f, 0x70, 0x6b, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x65, 0x72, 0x50, 0x6b,
+	0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x2c, 0x0a, 0x0c, 0x61, 0x6e, 0x6e, 0x6f, 0x75, 0x6e,
+	0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x41,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x52, 0x0c, 0x61, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65,
+	0x6d, 0x65, 0x6e, 0x74, 0x22, 0x7b, 0x0a, 0x0c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x12, 0x15, 0x0a, 0x06, 0x73, 0x65, 0x72, 0x5f, 0x70, 0x6b, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x65, 0x72, 0x50, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x6e,
+	0x6f, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63,
+	0x65, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x69, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x73, 0x69, 0x67, 0x12, 0x2c, 0x0a, 0x0c, 0x61, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x41, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x52, 0x0c, 0x61, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x6d, 0x65, 0x6e,
+	0x74, 0x22, 0x37, 0x0a, 0x0a, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x41, 0x63, 0x6b, 0x12,
+	0x17, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x5f, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x61, 0x64, 0x64, 0x72, 0x4d, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x69, 0x67, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x73, 0x69, 0x67, 0x22, 0x40, 0x0a, 0x0f, 0x44, 0x69,
+	0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a,
+	0x07, 0x61, 0x64, 0x64, 0x72, 0x5f, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x61, 0x64, 0x64, 0x72, 0x4d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x22, 0x21, 0x0a, 0x0d,
+	0x44, 0x69, 0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x10, 0x0a,
+	0x03, 0x73, 0x69, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x73, 0x69, 0x67, 0x22,
+	0xa3, 0x01, 0x0a, 0x07, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x61,
+	0x64, 0x64, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x64, 0x72, 0x12,
+	0x1b, 0x0a, 0x09, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x08, 0x6c, 0x61, 0x73, 0x74, 0x53, 0x65, 0x65, 0x6e, 0x12, 0x15, 0x0a, 0x06,
+	0x73, 0x65, 0x72, 0x5f, 0x70, 0x6b, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x65,
+	0x72, 0x50, 0x6b, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x69, 0x67, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x73, 0x69, 0x67, 0x12, 0x20, 0x0a, 0x0c, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x65, 0x6e,
+	0x5f, 0x6e, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x53,
+	0x65, 0x65, 0x6e, 0x4e, 0x73, 0x22, 0x2b, 0x0a, 0x09, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x65, 0x73, 0x12, 0x1e, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x08, 0x2e, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x52, 0x05, 0x61, 0x64, 0x64,
+	0x72, 0x73, 0x22, 0x4a, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d,
+	0x69, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12,
+	0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x65, 0x6e, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x65, 0x65, 0x6e, 0x41, 0x66, 0x74, 0x65, 0x72, 0x22, 0x44,
+	0x0a, 0x14, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x14,
+	0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x22, 0x4e, 0x0a, 0x0b, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x50,
+	0x61, 0x67, 0x65, 0x12, 0x1e, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x08, 0x2e, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x52, 0x05, 0x61, 0x64,
+	0x64, 0x72, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x63, 0x75, 0x72, 0x73,
+	0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6e, 0x65, 0x78, 0x74, 0x43, 0x75,
+	0x72, 0x73, 0x6f, 0x72, 0x22, 0x2a, 0x0a, 0x0c, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72,
+	0x22, 0x4e, 0x0a, 0x0b, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x63, 0x65, 0x72, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63,
+	0x65, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x78, 0x35, 0x30, 0x39, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x04, 0x78, 0x35, 0x30, 0x39, 0x12, 0x17, 0x0a, 0x07, 0x63, 0x61, 0x5f, 0x78, 0x35,
+	0x30, 0x39, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x63, 0x61, 0x58, 0x35, 0x30, 0x39,
+	0x22, 0x34, 0x0a, 0x0a, 0x45, 0x6e, 0x63, 0x4b, 0x65, 0x79, 0x73, 0x4d, 0x65, 0x6d, 0x12, 0x26,
+	0x0a, 0x0e, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x73, 0x74, 0x75, 0x66, 0x66,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65,
+	0x64, 0x73, 0x74, 0x75, 0x66, 0x66, 0x22, 0x2d, 0x0a, 0x07, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x49,
+	0x4d, 0x12, 0x22, 0x0a, 0x0c, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x6d, 0x73,
+	0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74,
+	0x65, 0x64, 0x6d, 0x73, 0x67, 0x22, 0x38, 0x0a, 0x0b, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x5f, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x64, 0x64, 0x72, 0x4d, 0x65, 0x12, 0x10, 0x0a,
+	0x03, 0x73, 0x65, 0x71, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x73, 0x65, 0x71, 0x22,
+	0x53, 0x0a, 0x0e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x17, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x5f, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x61, 0x64, 0x64, 0x72, 0x4d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61,
+	0x72, 0x67, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x65, 0x71, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x03, 0x73, 0x65, 0x71, 0x22, 0x17, 0x0a, 0x03, 0x41, 0x63, 0x6b, 0x12, 0x10, 0x0a, 0x03, 0x73,
+	0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x73, 0x65, 0x71, 0x22, 0x2d, 0x0a,
+	0x07, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x64, 0x64, 0x72,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x64, 0x64, 0x72, 0x22, 0x68, 0x0a, 0x09,
+	0x44, 0x68, 0x74, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x15, 0x0a, 0x06, 0x73, 0x65, 0x72,
+	0x5f, 0x70, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73, 0x65, 0x72, 0x50, 0x6b,
+	0x12, 0x14, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x05, 0x61, 0x64, 0x64, 0x72, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x69, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x73, 0x69, 0x67, 0x22, 0x47, 0x0a, 0x0b, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x20, 0x0a, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x52,
+	0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x22,
+	0x35, 0x0a, 0x0d, 0x46, 0x69, 0x6e, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79,
+	0x12, 0x24, 0x0a, 0x08, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x08, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x52, 0x08, 0x63, 0x6f,
+	0x6e, 0x74, 0x61, 0x63, 0x74, 0x73, 0x22, 0x5a, 0x0a, 0x0e, 0x46, 0x69, 0x6e, 0x64, 0x56, 0x61,
+	0x6c, 0x75, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x22, 0x0a, 0x06, 0x72, 0x65, 0x63, 0x6f,
+	0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x44, 0x68, 0x74, 0x52, 0x65,
+	0x63, 0x6f, 0x72, 0x64, 0x52, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x24, 0x0a, 0x08,
+	0x63, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08,
+	0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x52, 0x08, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x63,
+	0x74, 0x73, 0x22, 0x54, 0x0a, 0x0c, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x20, 0x0a, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x08, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x63, 0x74, 0x52, 0x06, 0x73, 0x65,
+	0x6e, 0x64, 0x65, 0x72, 0x12, 0x22, 0x0a, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x44, 0x68, 0x74, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64,
+	0x52, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x32, 0xd4, 0x04, 0x0a, 0x09, 0x42, 0x72, 0x75,
+	0x6e, 0x6f, 0x43, 0x6f, 0x69, 0x6e, 0x12, 0x29, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x0f, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x12, 0x1d, 0x0a, 0x06, 0x56, 0x65, 0x72, 0x41, 0x63, 0x6b, 0x12, 0x0b, 0x2e, 0x56, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x41, 0x63, 0x6b, 0x1a, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x12, 0x2c, 0x0a, 0x08, 0x44, 0x69, 0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x12, 0x10, 0x2e, 0x44,
+	0x69, 0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e,
+	0x2e, 0x44, 0x69, 0x61, 0x6c, 0x42, 0x61, 0x63, 0x6b, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x23,
 	0x0a, 0x0d, 0x53, 0x65, 0x6e, 0x64, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x12,
 	0x0a, 0x2e, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x1a, 0x06, 0x2e, 0x45, 0x6d,
-	0x70, 0x74, 0x79, 0x12, 0x22, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73,
-	0x73, 0x65, 0x73, 0x12, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0a, 0x2e, 0x41, 0x64,
-	0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x12, 0x27, 0x0a, 0x08, 0x52, 0x65, 0x67, 0x69, 0x73,
-	0x74, 0x65, 0x72, 0x12, 0x0d, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x1a, 0x0c, 0x2e, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x65,
-	0x12, 0x20, 0x0a, 0x09, 0x41, 0x64, 0x64, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x0b, 0x2e,
-	0x45, 0x6e, 0x63, 0x4b, 0x65, 0x79, 0x73, 0x4d, 0x65, 0x6d, 0x1a, 0x06, 0x2e, 0x45, 0x6d, 0x70,
-	0x74, 0x79, 0x12, 0x21, 0x0a, 0x0a, 0x4b, 0x69, 0x63, 0x6b, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72,
-	0x12, 0x0b, 0x2e, 0x45, 0x6e, 0x63, 0x4b, 0x65, 0x79, 0x73, 0x4d, 0x65, 0x6d, 0x1a, 0x06, 0x2e,
-	0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x20, 0x0a, 0x0c, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x4d, 0x65,
-	0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x08, 0x2e, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x4d, 0x1a,
-	0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x15, 0x5a, 0x13, 0x42, 0x72, 0x75, 0x6e, 0x6f,
-	0x43, 0x6f, 0x69, 0x6e, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x70, 0x74, 0x79, 0x12, 0x30, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x65, 0x73, 0x12, 0x14, 0x2e, 0x47, 0x65, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0a, 0x2e, 0x41, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x65, 0x73, 0x12, 0x34, 0x0a, 0x0d, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x12, 0x15, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e,
+	0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x50, 0x61, 0x67, 0x65, 0x12, 0x27, 0x0a, 0x08, 0x52,
+	0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x12, 0x0d, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x0c, 0x2e, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69,
+	0x63, 0x61, 0x74, 0x65, 0x12, 0x20, 0x0a, 0x09, 0x41, 0x64, 0x64, 0x4d, 0x65, 0x6d, 0x62, 0x65,
+	0x72, 0x12, 0x0b, 0x2e, 0x45, 0x6e, 0x63, 0x4b, 0x65, 0x79, 0x73, 0x4d, 0x65, 0x6d, 0x1a, 0x06,
+	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x21, 0x0a, 0x0a, 0x4b, 0x69, 0x63, 0x6b, 0x4d, 0x65,
+	0x6d, 0x62, 0x65, 0x72, 0x12, 0x0b, 0x2e, 0x45, 0x6e, 0x63, 0x4b, 0x65, 0x79, 0x73, 0x4d, 0x65,
+	0x6d, 0x1a, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x20, 0x0a, 0x0c, 0x47, 0x72, 0x6f,
+	0x75, 0x70, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x08, 0x2e, 0x47, 0x72, 0x6f, 0x75,
+	0x70, 0x49, 0x4d, 0x1a, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x1a, 0x0a, 0x04, 0x50,
+	0x69, 0x6e, 0x67, 0x12, 0x0c, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x04, 0x2e, 0x41, 0x63, 0x6b, 0x12, 0x20, 0x0a, 0x07, 0x50, 0x69, 0x6e, 0x67, 0x52,
+	0x65, 0x71, 0x12, 0x0f, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x04, 0x2e, 0x41, 0x63, 0x6b, 0x12, 0x28, 0x0a, 0x08, 0x46, 0x69, 0x6e,
+	0x64, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x0c, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x12, 0x2a, 0x0a, 0x09, 0x46, 0x69, 0x6e, 0x64, 0x56, 0x61, 0x6c, 0x75, 0x65,
+	0x12, 0x0c, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f,
+	0x2e, 0x46, 0x69, 0x6e, 0x64, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x1e, 0x0a, 0x05, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x12, 0x0d, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x06, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42,
+	0x15, 0x5a, 0x13, 0x42, 0x72, 0x75, 0x6e, 0x6f, 0x43, 0x6f, 0x69, 0x6e, 0x2f, 0x70, 0x6b, 0x67,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -486,38 +1551,79 @@ func file_broseph_proto_rawDescGZIP() []byte {
 	return file_broseph_proto_rawDescData
 }
 
-var file_broseph_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_broseph_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
 var file_broseph_proto_goTypes = []interface{}{
-	(*Empty)(nil),          // 0: Empty
-	(*VersionRequest)(nil), // 1: VersionRequest
-	(*Address)(nil),        // 2: Address
-	(*Addresses)(nil),      // 3: Addresses
-	(*Registration)(nil),   // 4: Registration
-	(*Certificate)(nil),    // 5: Certificate
-	(*EncKeysMem)(nil),     // 6: EncKeysMem
-	(*GroupIM)(nil),        // 7: GroupIM
+	(*Empty)(nil),                // 0: Empty
+	(*VersionRequest)(nil),       // 1: VersionRequest
+	(*VersionReply)(nil),         // 2: VersionReply
+	(*VersionAck)(nil),           // 3: VersionAck
+	(*DialBackRequest)(nil),      // 4: DialBackRequest
+	(*DialBackReply)(nil),        // 5: DialBackReply
+	(*Address)(nil),              // 6: Address
+	(*Addresses)(nil),            // 7: Addresses
+	(*GetAddressesRequest)(nil),  // 8: GetAddressesRequest
+	(*ListAddressesRequest)(nil), // 9: ListAddressesRequest
+	(*AddressPage)(nil),          // 10: AddressPage
+	(*Registration)(nil),         // 11: Registration
+	(*Certificate)(nil),          // 12: Certificate
+	(*EncKeysMem)(nil),           // 13: EncKeysMem
+	(*GroupIM)(nil),              // 14: GroupIM
+	(*PingRequest)(nil),          // 15: PingRequest
+	(*PingReqRequest)(nil),       // 16: PingReqRequest
+	(*Ack)(nil),                  // 17: Ack
+	(*Contact)(nil),              // 18: Contact
+	(*DhtRecord)(nil),            // 19: DhtRecord
+	(*FindRequest)(nil),          // 20: FindRequest
+	(*FindNodeReply)(nil),        // 21: FindNodeReply
+	(*FindValueReply)(nil),       // 22: FindValueReply
+	(*StoreRequest)(nil),         // 23: StoreRequest
 }
 var file_broseph_proto_depIdxs = []int32{
-	2, // 0: Addresses.addrs:type_name -> Address
-	1, // 1: BrunoCoin.Version:input_type -> VersionRequest
-	3, // 2: BrunoCoin.SendAddresses:input_type -> Addresses
-	0, // 3: BrunoCoin.GetAddresses:input_type -> Empty
-	4, // 4: BrunoCoin.Register:input_type -> Registration
-	6, // 5: BrunoCoin.AddMember:input_type -> EncKeysMem
-	6, // 6: BrunoCoin.KickMember:input_type -> EncKeysMem
-	7, // 7: BrunoCoin.GroupMessage:input_type -> GroupIM
-	0, // 8: BrunoCoin.Version:output_type -> Empty
-	0, // 9: BrunoCoin.SendAddresses:output_type -> Empty
-	3, // 10: BrunoCoin.GetAddresses:output_type -> Addresses
-	5, // 11: BrunoCoin.Register:output_type -> Certificate
-	0, // 12: BrunoCoin.AddMember:output_type -> Empty
-	0, // 13: BrunoCoin.KickMember:output_type -> Empty
-	0, // 14: BrunoCoin.GroupMessage:output_type -> Empty
-	8, // [8:15] is the sub-list for method output_type
-	1, // [1:8] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	6,  // 0: VersionRequest.announcement:type_name -> Address
+	6,  // 1: VersionReply.announcement:type_name -> Address
+	6,  // 2: Addresses.addrs:type_name -> Address
+	6,  // 3: AddressPage.addrs:type_name -> Address
+	18, // 4: FindRequest.sender:type_name -> Contact
+	18, // 5: FindNodeReply.contacts:type_name -> Contact
+	19, // 6: FindValueReply.record:type_name -> DhtRecord
+	18, // 7: FindValueReply.contacts:type_name -> Contact
+	18, // 8: StoreRequest.sender:type_name -> Contact
+	19, // 9: StoreRequest.record:type_name -> DhtRecord
+	1,  // 10: BrunoCoin.Version:input_type -> VersionRequest
+	3,  // 11: BrunoCoin.VerAck:input_type -> VersionAck
+	4,  // 12: BrunoCoin.DialBack:input_type -> DialBackRequest
+	7,  // 13: BrunoCoin.SendAddresses:input_type -> Addresses
+	8,  // 14: BrunoCoin.GetAddresses:input_type -> GetAddressesRequest
+	9,  // 15: BrunoCoin.ListAddresses:input_type -> ListAddressesRequest
+	11, // 16: BrunoCoin.Register:input_type -> Registration
+	13, // 17: BrunoCoin.AddMember:input_type -> EncKeysMem
+	13, // 18: BrunoCoin.KickMember:input_type -> EncKeysMem
+	14, // 19: BrunoCoin.GroupMessage:input_type -> GroupIM
+	15, // 20: BrunoCoin.Ping:input_type -> PingRequest
+	16, // 21: BrunoCoin.PingReq:input_type -> PingReqRequest
+	20, // 22: BrunoCoin.FindNode:input_type -> FindRequest
+	20, // 23: BrunoCoin.FindValue:input_type -> FindRequest
+	23, // 24: BrunoCoin.Store:input_type -> StoreRequest
+	2,  // 25: BrunoCoin.Version:output_type -> VersionReply
+	0,  // 26: BrunoCoin.VerAck:output_type -> Empty
+	5,  // 27: BrunoCoin.DialBack:output_type -> DialBackReply
+	0,  // 28: BrunoCoin.SendAddresses:output_type -> Empty
+	7,  // 29: BrunoCoin.GetAddresses:output_type -> Addresses
+	10, // 30: BrunoCoin.ListAddresses:output_type -> AddressPage
+	12, // 31: BrunoCoin.Register:output_type -> Certificate
+	0,  // 32: BrunoCoin.AddMember:output_type -> Empty
+	0,  // 33: BrunoCoin.KickMember:output_type -> Empty
+	0,  // 34: BrunoCoin.GroupMessage:output_type -> Empty
+	17, // 35: BrunoCoin.Ping:output_type -> Ack
+	17, // 36: BrunoCoin.PingReq:output_type -> Ack
+	21, // 37: BrunoCoin.FindNode:output_type -> FindNodeReply
+	22, // 38: BrunoCoin.FindValue:output_type -> FindValueReply
+	0,  // 39: BrunoCoin.Store:output_type -> Empty
+	25, // [25:40] is the sub-list for method output_type
+	10, // [10:25] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_broseph_proto_init() }
@@ -551,7 +1657,7 @@ func file_broseph_proto_init() {
 			}
 		}
 		file_broseph_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Address); i {
+			switch v := v.(*VersionReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -563,7 +1669,7 @@ func file_broseph_proto_init() {
 			}
 		}
 		file_broseph_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Addresses); i {
+			switch v := v.(*VersionAck); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -575,7 +1681,7 @@ func file_broseph_proto_init() {
 			}
 		}
 		file_broseph_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Registration); i {
+			switch v := v.(*DialBackRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -587,7 +1693,7 @@ func file_broseph_proto_init() {
 			}
 		}
 		file_broseph_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Certificate); i {
+			switch v := v.(*DialBackReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -599,7 +1705,7 @@ func file_broseph_proto_init() {
 			}
 		}
 		file_broseph_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*EncKeysMem); i {
+			switch v := v.(*Address); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -611,6 +1717,90 @@ func file_broseph_proto_init() {
 			}
 		}
 		file_broseph_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Addresses); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetAddressesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListAddressesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddressPage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Registration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Certificate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EncKeysMem); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GroupIM); i {
 			case 0:
 				return &v.state
@@ -622,6 +1812,114 @@ func file_broseph_proto_init() {
 				return nil
 			}
 		}
+		file_broseph_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PingReqRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Ack); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Contact); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DhtRecord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindNodeReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindValueReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_broseph_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StoreRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -629,7 +1927,7 @@ func file_broseph_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_broseph_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
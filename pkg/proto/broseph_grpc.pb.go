@@ -24,27 +24,48 @@ const _ = grpc.SupportPackageIsVersion7
 
 const (
 	BrunoCoin_Version_FullMethodName       = "/BrunoCoin/Version"
+	BrunoCoin_VerAck_FullMethodName        = "/BrunoCoin/VerAck"
+	BrunoCoin_DialBack_FullMethodName      = "/BrunoCoin/DialBack"
 	BrunoCoin_SendAddresses_FullMethodName = "/BrunoCoin/SendAddresses"
 	BrunoCoin_GetAddresses_FullMethodName  = "/BrunoCoin/GetAddresses"
+	BrunoCoin_ListAddresses_FullMethodName = "/BrunoCoin/ListAddresses"
 	BrunoCoin_Register_FullMethodName      = "/BrunoCoin/Register"
 	BrunoCoin_AddMember_FullMethodName     = "/BrunoCoin/AddMember"
 	BrunoCoin_KickMember_FullMethodName    = "/BrunoCoin/KickMember"
 	BrunoCoin_GroupMessage_FullMethodName  = "/BrunoCoin/GroupMessage"
+	BrunoCoin_Ping_FullMethodName          = "/BrunoCoin/Ping"
+	BrunoCoin_PingReq_FullMethodName       = "/BrunoCoin/PingReq"
+	BrunoCoin_FindNode_FullMethodName      = "/BrunoCoin/FindNode"
+	BrunoCoin_FindValue_FullMethodName     = "/BrunoCoin/FindValue"
+	BrunoCoin_Store_FullMethodName         = "/BrunoCoin/Store"
 )
 
 // BrunoCoinClient is the client API for BrunoCoin service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BrunoCoinClient interface {
-	Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*Empty, error)
+	Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionReply, error)
+	VerAck(ctx context.Context, in *VersionAck, opts ...grpc.CallOption) (*Empty, error)
+	// Proves to the receiver of a Version that the initiator is reachable
+	// at the address it claimed
+	DialBack(ctx context.Context, in *DialBackRequest, opts ...grpc.CallOption) (*DialBackReply, error)
 	// Sends know addresses to neighbors, forwarded from node to node
 	SendAddresses(ctx context.Context, in *Addresses, opts ...grpc.CallOption) (*Empty, error)
-	// Gets neighbor addresses from node (can be multicast with static addr_me)
-	GetAddresses(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Addresses, error)
+	// Gets a random sample of neighbor addresses from node
+	GetAddresses(ctx context.Context, in *GetAddressesRequest, opts ...grpc.CallOption) (*Addresses, error)
+	// Pages through every address the node knows, for authorized callers
+	ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...grpc.CallOption) (*AddressPage, error)
 	Register(ctx context.Context, in *Registration, opts ...grpc.CallOption) (*Certificate, error)
 	AddMember(ctx context.Context, in *EncKeysMem, opts ...grpc.CallOption) (*Empty, error)
 	KickMember(ctx context.Context, in *EncKeysMem, opts ...grpc.CallOption) (*Empty, error)
 	GroupMessage(ctx context.Context, in *GroupIM, opts ...grpc.CallOption) (*Empty, error)
+	// Liveness probes for failure detection
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*Ack, error)
+	PingReq(ctx context.Context, in *PingReqRequest, opts ...grpc.CallOption) (*Ack, error)
+	// Kademlia lookups of node identities
+	FindNode(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindNodeReply, error)
+	FindValue(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindValueReply, error)
+	Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type brunoCoinClient struct {
@@ -55,8 +76,8 @@ func NewBrunoCoinClient(cc grpc.ClientConnInterface) BrunoCoinClient {
 	return &brunoCoinClient{cc}
 }
 
-func (c *brunoCoinClient) Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
+func (c *brunoCoinClient) Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionReply, error) {
+	out := new(VersionReply)
 	err := c.cc.Invoke(ctx, BrunoCoin_Version_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -64,6 +85,24 @@ func (c *brunoCoinClient) Version(ctx context.Context, in *VersionRequest, opts
 	return out, nil
 }
 
+func (c *brunoCoinClient) VerAck(ctx context.Context, in *VersionAck, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, BrunoCoin_VerAck_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *brunoCoinClient) DialBack(ctx context.Context, in *DialBackRequest, opts ...grpc.CallOption) (*DialBackReply, error) {
+	out := new(DialBackReply)
+	err := c.cc.Invoke(ctx, BrunoCoin_DialBack_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *brunoCoinClient) SendAddresses(ctx context.Context, in *Addresses, opts ...grpc.CallOption) (*Empty, error) {
 	out := new(Empty)
 	err := c.cc.Invoke(ctx, BrunoCoin_SendAddresses_FullMethodName, in, out, opts...)
@@ -73,7 +112,7 @@ func (c *brunoCoinClient) SendAddresses(ctx context.Context, in *Addresses, opts
 	return out, nil
 }
 
-func (c *brunoCoinClient) GetAddresses(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Addresses, error) {
+func (c *brunoCoinClient) GetAddresses(ctx context.Context, in *GetAddressesRequest, opts ...grpc.CallOption) (*Addresses, error) {
 	out := new(Addresses)
 	err := c.cc.Invoke(ctx, BrunoCoin_GetAddresses_FullMethodName, in, out, opts...)
 	if err != nil {
@@ -82,6 +121,15 @@ func (c *brunoCoinClient) GetAddresses(ctx context.Context, in *Empty, opts ...g
 	return out, nil
 }
 
+func (c *brunoCoinClient) ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...grpc.CallOption) (*AddressPage, error) {
+	out := new(AddressPage)
+	err := c.cc.Invoke(ctx, BrunoCoin_ListAddresses_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *brunoCoinClient) Register(ctx context.Context, in *Registration, opts ...grpc.CallOption) (*Certificate, error) {
 	out := new(Certificate)
 	err := c.cc.Invoke(ctx, BrunoCoin_Register_FullMethodName, in, out, opts...)
@@ -118,19 +166,77 @@ func (c *brunoCoinClient) GroupMessage(ctx context.Context, in *GroupIM, opts ..
 	return out, nil
 }
 
+func (c *brunoCoinClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*Ack, error) {
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, BrunoCoin_Ping_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *brunoCoinClient) PingReq(ctx context.Context, in *PingReqRequest, opts ...grpc.CallOption) (*Ack, error) {
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, BrunoCoin_PingReq_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *brunoCoinClient) FindNode(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindNodeReply, error) {
+	out := new(FindNodeReply)
+	err := c.cc.Invoke(ctx, BrunoCoin_FindNode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *brunoCoinClient) FindValue(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*FindValueReply, error) {
+	out := new(FindValueReply)
+	err := c.cc.Invoke(ctx, BrunoCoin_FindValue_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *brunoCoinClient) Store(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, BrunoCoin_Store_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BrunoCoinServer is the server API for BrunoCoin service.
 // All implementations must embed UnimplementedBrunoCoinServer
 // for forward compatibility
 type BrunoCoinServer interface {
-	Version(context.Context, *VersionRequest) (*Empty, error)
+	Version(context.Context, *VersionRequest) (*VersionReply, error)
+	VerAck(context.Context, *VersionAck) (*Empty, error)
+	// Proves to the receiver of a Version that the initiator is reachable
+	// at the address it claimed
+	DialBack(context.Context, *DialBackRequest) (*DialBackReply, error)
 	// Sends know addresses to neighbors, forwarded from node to node
 	SendAddresses(context.Context, *Addresses) (*Empty, error)
-	// Gets neighbor addresses from node (can be multicast with static addr_me)
-	GetAddresses(context.Context, *Empty) (*Addresses, error)
+	// Gets a random sample of neighbor addresses from node
+	GetAddresses(context.Context, *GetAddressesRequest) (*Addresses, error)
+	// Pages through every address the node knows, for authorized callers
+	ListAddresses(context.Context, *ListAddressesRequest) (*AddressPage, error)
 	Register(context.Context, *Registration) (*Certificate, error)
 	AddMember(context.Context, *EncKeysMem) (*Empty, error)
 	KickMember(context.Context, *EncKeysMem) (*Empty, error)
 	GroupMessage(context.Context, *GroupIM) (*Empty, error)
+	// Liveness probes for failure detection
+	Ping(context.Context, *PingRequest) (*Ack, error)
+	PingReq(context.Context, *PingReqRequest) (*Ack, error)
+	// Kademlia lookups of node identities
+	FindNode(context.Context, *FindRequest) (*FindNodeReply, error)
+	FindValue(context.Context, *FindRequest) (*FindValueReply, error)
+	Store(context.Context, *StoreRequest) (*Empty, error)
 	mustEmbedUnimplementedBrunoCoinServer()
 }
 
@@ -138,15 +244,24 @@ type BrunoCoinServer interface {
 type UnimplementedBrunoCoinServer struct {
 }
 
-func (UnimplementedBrunoCoinServer) Version(context.Context, *VersionRequest) (*Empty, error) {
+func (UnimplementedBrunoCoinServer) Version(context.Context, *VersionRequest) (*VersionReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
 }
+func (UnimplementedBrunoCoinServer) VerAck(context.Context, *VersionAck) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerAck not implemented")
+}
+func (UnimplementedBrunoCoinServer) DialBack(context.Context, *DialBackRequest) (*DialBackReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DialBack not implemented")
+}
 func (UnimplementedBrunoCoinServer) SendAddresses(context.Context, *Addresses) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendAddresses not implemented")
 }
-func (UnimplementedBrunoCoinServer) GetAddresses(context.Context, *Empty) (*Addresses, error) {
+func (UnimplementedBrunoCoinServer) GetAddresses(context.Context, *GetAddressesRequest) (*Addresses, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetAddresses not implemented")
 }
+func (UnimplementedBrunoCoinServer) ListAddresses(context.Context, *ListAddressesRequest) (*AddressPage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAddresses not implemented")
+}
 func (UnimplementedBrunoCoinServer) Register(context.Context, *Registration) (*Certificate, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
 }
@@ -159,6 +274,21 @@ func (UnimplementedBrunoCoinServer) KickMember(context.Context, *EncKeysMem) (*E
 func (UnimplementedBrunoCoinServer) GroupMessage(context.Context, *GroupIM) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GroupMessage not implemented")
 }
+func (UnimplementedBrunoCoinServer) Ping(context.Context, *PingRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedBrunoCoinServer) PingReq(context.Context, *PingReqRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PingReq not implemented")
+}
+func (UnimplementedBrunoCoinServer) FindNode(context.Context, *FindRequest) (*FindNodeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindNode not implemented")
+}
+func (UnimplementedBrunoCoinServer) FindValue(context.Context, *FindRequest) (*FindValueReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindValue not implemented")
+}
+func (UnimplementedBrunoCoinServer) Store(context.Context, *StoreRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Store not implemented")
+}
 func (UnimplementedBrunoCoinServer) mustEmbedUnimplementedBrunoCoinServer() {}
 
 // UnsafeBrunoCoinServer may be embedded to opt out of forward compatibility for this service.
@@ -190,6 +320,42 @@ func _BrunoCoin_Version_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BrunoCoin_VerAck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionAck)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrunoCoinServer).VerAck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BrunoCoin_VerAck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrunoCoinServer).VerAck(ctx, req.(*VersionAck))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BrunoCoin_DialBack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DialBackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrunoCoinServer).DialBack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BrunoCoin_DialBack_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrunoCoinServer).DialBack(ctx, req.(*DialBackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _BrunoCoin_SendAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Addresses)
 	if err := dec(in); err != nil {
@@ -209,7 +375,7 @@ func _BrunoCoin_SendAddresses_Handler(srv interface{}, ctx context.Context, dec
 }
 
 func _BrunoCoin_GetAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(GetAddressesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -221,7 +387,25 @@ func _BrunoCoin_GetAddresses_Handler(srv interface{}, ctx context.Context, dec f
 		FullMethod: BrunoCoin_GetAddresses_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BrunoCoinServer).GetAddresses(ctx, req.(*Empty))
+		return srv.(BrunoCoinServer).GetAddresses(ctx, req.(*GetAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BrunoCoin_ListAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrunoCoinServer).ListAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BrunoCoin_ListAddresses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrunoCoinServer).ListAddresses(ctx, req.(*ListAddressesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -298,6 +482,96 @@ func _BrunoCoin_GroupMessage_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BrunoCoin_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrunoCoinServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BrunoCoin_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrunoCoinServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BrunoCoin_PingReq_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingReqRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrunoCoinServer).PingReq(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BrunoCoin_PingReq_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrunoCoinServer).PingReq(ctx, req.(*PingReqRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BrunoCoin_FindNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrunoCoinServer).FindNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BrunoCoin_FindNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrunoCoinServer).FindNode(ctx, req.(*FindRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BrunoCoin_FindValue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrunoCoinServer).FindValue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BrunoCoin_FindValue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrunoCoinServer).FindValue(ctx, req.(*FindRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BrunoCoin_Store_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrunoCoinServer).Store(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BrunoCoin_Store_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrunoCoinServer).Store(ctx, req.(*StoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BrunoCoin_ServiceDesc is the grpc.ServiceDesc for BrunoCoin service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -309,6 +583,14 @@ var BrunoCoin_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Version",
 			Handler:    _BrunoCoin_Version_Handler,
 		},
+		{
+			MethodName: "VerAck",
+			Handler:    _BrunoCoin_VerAck_Handler,
+		},
+		{
+			MethodName: "DialBack",
+			Handler:    _BrunoCoin_DialBack_Handler,
+		},
 		{
 			MethodName: "SendAddresses",
 			Handler:    _BrunoCoin_SendAddresses_Handler,
@@ -317,6 +599,10 @@ var BrunoCoin_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetAddresses",
 			Handler:    _BrunoCoin_GetAddresses_Handler,
 		},
+		{
+			MethodName: "ListAddresses",
+			Handler:    _BrunoCoin_ListAddresses_Handler,
+		},
 		{
 			MethodName: "Register",
 			Handler:    _BrunoCoin_Register_Handler,
@@ -333,6 +619,26 @@ var BrunoCoin_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GroupMessage",
 			Handler:    _BrunoCoin_GroupMessage_Handler,
 		},
+		{
+			MethodName: "Ping",
+			Handler:    _BrunoCoin_Ping_Handler,
+		},
+		{
+			MethodName: "PingReq",
+			Handler:    _BrunoCoin_PingReq_Handler,
+		},
+		{
+			MethodName: "FindNode",
+			Handler:    _BrunoCoin_FindNode_Handler,
+		},
+		{
+			MethodName: "FindValue",
+			Handler:    _BrunoCoin_FindValue_Handler,
+		},
+		{
+			MethodName: "Store",
+			Handler:    _BrunoCoin_Store_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "broseph.proto",
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// maxKeys bounds how many per-key buckets are tracked before full
+// (i.e. idle) buckets are forgotten.
+const maxKeys = 10000
+
+// bucket is a token bucket refilled at rate tokens per second up to
+// burst tokens.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time, rate, burst float64) {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
+
+// Limiter enforces a per-key, a per-host and a global token bucket.
+// A rate of 0 disables the corresponding limit.
+type Limiter struct {
+	peerRate, peerBurst     float64
+	hostRate, hostBurst     float64
+	globalRate, globalBurst float64
+
+	peers  map[string]*bucket
+	hosts  map[string]*bucket
+	global bucket
+	sync.Mutex
+}
+
+func New(peerRate float64, peerBurst int, globalRate float64, globalBurst int) *Limiter {
+	now := time.Now()
+	return &Limiter{
+		peerRate:    peerRate,
+		peerBurst:   float64(peerBurst),
+		globalRate:  globalRate,
+		globalBurst: float64(globalBurst),
+		peers:       make(map[string]*bucket),
+		hosts:       make(map[string]*bucket),
+		global:      bucket{tokens: float64(globalBurst), last: now},
+	}
+}
+
+// SetHostLimit sets the rate and burst of the per-host buckets charged
+// by AllowFrom. Hosts are limited from the first request after the
+// call.
+func (l *Limiter) SetHostLimit(rate float64, burst int) {
+	l.Lock()
+	defer l.Unlock()
+	l.hostRate = rate
+	l.hostBurst = float64(burst)
+	l.hosts = make(map[string]*bucket)
+}
+
+// Allow reports whether a request from key costing cost tokens may
+// proceed, and consumes the tokens if so.
+func (l *Limiter) Allow(key string, cost float64) bool {
+	return l.AllowFrom(key, "", cost)
+}
+
+// AllowFrom is Allow for a request from key at host. The host's bucket
+// must allow it too, so a caller cannot escape its limit by changing
+// keys. An empty host is not limited.
+func (l *Limiter) AllowFrom(key, host string, cost float64) bool {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	var charged []*bucket
+	if l.peerRate > 0 {
+		b := l.bucket(l.peers, key, now, l.peerRate, l.peerBurst)
+		if b.tokens < cost {
+			return false
+		}
+		charged = append(charged, b)
+	}
+	if l.hostRate > 0 && host != "" {
+		b := l.bucket(l.hosts, host, now, l.hostRate, l.hostBurst)
+		if b.tokens < cost {
+			return false
+		}
+		charged = append(charged, b)
+	}
+	if l.globalRate > 0 {
+		l.global.refill(now, l.globalRate, l.globalBurst)
+		if l.global.tokens < cost {
+			return false
+		}
+		l.global.tokens -= cost
+	}
+	for _, b := range charged {
+		b.tokens -= cost
+	}
+	return true
+}
+
+// bucket returns the refilled bucket for key in m, adding a full one
+// if there is none. The caller must hold the lock.
+func (l *Limiter) bucket(m map[string]*bucket, key string, now time.Time, rate, burst float64) *bucket {
+	b := m[key]
+	if b == nil {
+		if len(m) >= maxKeys {
+			prune(m, now, rate, burst)
+		}
+		b = &bucket{tokens: burst, last: now}
+		m[key] = b
+	}
+	b.refill(now, rate, burst)
+	return b
+}
+
+// prune forgets buckets that have refilled completely, since a new
+// bucket would behave the same.
+func prune(m map[string]*bucket, now time.Time, rate, burst float64) {
+	for key, b := range m {
+		b.refill(now, rate, burst)
+		if b.tokens >= burst {
+			delete(m, key)
+		}
+	}
+}
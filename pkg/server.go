@@ -1,13 +1,21 @@
 package pkg
 
 import (
+	"crypto/rsa"
 	"errors"
 	"finalbruh/pkg/address"
-	"finalbruh/pkg/peer"
+	"finalbruh/pkg/address/addressdb"
+	"finalbruh/pkg/group"
+	"finalbruh/pkg/id"
+	"finalbruh/pkg/netaddr"
 	"finalbruh/pkg/proto"
 	"finalbruh/pkg/utils"
 	"fmt"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"sort"
 	"time"
 )
 
@@ -15,7 +23,7 @@ func (n *Node) peerCheck(addr string) error {
 	if n.PeerDb.Get(addr) == nil {
 		return errors.New("request from non-peered node")
 	}
-	err := n.PeerDb.UpdateLastSeen(addr, uint32(time.Now().UnixNano()))
+	err := n.PeerDb.UpdateLastSeen(addr, time.Now().UnixNano())
 	if err != nil {
 		fmt.Printf("ERROR {Node.peerCheck}: error" +
 			"when calling updatelastseen.\n")
@@ -23,84 +31,187 @@ func (n *Node) peerCheck(addr string) error {
 	return nil
 }
 
-func (n *Node) Version(ctx context.Context, in *proto.VersionRequest) (*proto.Empty, error) {
+// peerCaller returns nil if the caller of the RPC handled under ctx is
+// the peer at addr. Under TLS it must have connected with the key the
+// peer handshook with.
+func (n *Node) peerCaller(ctx context.Context, addr string) error {
+	p := n.PeerDb.Get(addr)
+	if p == nil {
+		return errors.New("request from non-peered node")
+	}
+	key, err := n.peerKey(ctx)
+	if err != nil {
+		return err
+	}
+	if key != nil && !key.Equal(p.PublicKey) {
+		return errors.New("caller key does not match the peer at its address")
+	}
+	return nil
+}
+
+// peerKey returns the key the caller proved ownership of during the
+// TLS handshake, or nil when the node runs insecurely.
+func (n *Node) peerKey(ctx context.Context) (*rsa.PublicKey, error) {
+	if n.Conf.Insecure {
+		return nil, nil
+	}
+	return id.PeerKey(ctx)
+}
+
+func (n *Node) Version(ctx context.Context, in *proto.VersionRequest) (*proto.VersionReply, error) {
 	if int(in.Version) != n.Conf.Version {
-		return &proto.Empty{}, nil
-	}
-	newAddr := address.New(in.AddrMe, uint32(time.Now().UnixNano()))
-	if n.AddrDb.Get(newAddr.Addr) != nil {
-		err := n.AddrDb.UpdateLastSeen(newAddr.Addr, newAddr.LastSeen)
-		if err != nil {
-			return &proto.Empty{}, nil
-		}
-	} else if err := n.AddrDb.Add(newAddr); err != nil {
-		return &proto.Empty{}, nil
-	}
-	key, _ := utils.DecodePublicKey(in.SerPk)
-	newPeer := peer.New(n.AddrDb.Get(newAddr.Addr), in.Version, key)
-	pendingVer := newPeer.Addr.SentVer != time.Time{} && newPeer.Addr.SentVer.Add(n.Conf.VerTimeout).After(time.Now())
-	if n.PeerDb.Add(newPeer) && !pendingVer {
-		newPeer.Addr.SentVer = time.Now()
-		kk, _ := utils.EncodePublicKey(&n.Id.PrivateKey.PublicKey)
-		_, err := newAddr.VersionRPC(&proto.VersionRequest{
-			Version: uint32(n.Conf.Version),
-			AddrYou: in.AddrMe,
-			AddrMe:  n.Addr,
-			SerPk:   kk,
-		})
-		if err != nil {
-			return &proto.Empty{}, err
-		}
+		return &proto.VersionReply{}, errors.New("unsupported version")
 	}
-	return &proto.Empty{}, nil
+	key, err := utils.DecodePublicKey(in.SerPk)
+	if err != nil {
+		n.misbehaving(ctx, scoreBadHandshake, "undecodable version key")
+		return &proto.VersionReply{}, err
+	}
+	tlsKey, err := n.peerKey(ctx)
+	if err != nil {
+		return &proto.VersionReply{}, err
+	}
+	if tlsKey != nil && !tlsKey.Equal(key) {
+		n.misbehaving(ctx, scoreBadSignature, "version key does not match connection identity")
+		return &proto.VersionReply{}, errors.New("version key does not match connection identity")
+	}
+	if hp, err := netaddr.Parse(in.AddrMe); err != nil || !hp.Dialable() {
+		n.misbehaving(ctx, scoreBadHandshake, "invalid sender address")
+		return &proto.VersionReply{}, errors.New("invalid sender address")
+	}
+	if len(in.Nonce) < nonceSize {
+		n.misbehaving(ctx, scoreBadHandshake, "version nonce too short")
+		return &proto.VersionReply{}, errors.New("version nonce too short")
+	}
+	nonce, err := utils.RandomBytes(nonceSize)
+	if err != nil {
+		return &proto.VersionReply{}, err
+	}
+	// The challenge is recorded before anything is signed, so a
+	// Version that is refused costs little.
+	err = n.handshakes.await(in.AddrMe, &challenge{
+		nonce:   nonce,
+		key:     key,
+		version: in.Version,
+		ann:     announcement(in.Announcement, in.AddrMe, key),
+		expires: time.Now().Add(n.Conf.VerTimeout),
+	})
+	if err != nil {
+		return &proto.VersionReply{}, err
+	}
+	sig, err := utils.Sign(n.Id.PrivateKey, handshakeMsg(in.Nonce, n.Addr, in.AddrMe))
+	if err != nil {
+		return &proto.VersionReply{}, err
+	}
+	myKey, _ := utils.EncodePublicKey(&n.Id.PrivateKey.PublicKey)
+	ann, err := address.Announce(n.Id.PrivateKey, n.Addr)
+	if err != nil {
+		return &proto.VersionReply{}, err
+	}
+	return &proto.VersionReply{SerPk: myKey, Nonce: nonce, Sig: sig, Announcement: ann.Serialize()}, nil
+}
+
+func (n *Node) VerAck(ctx context.Context, in *proto.VersionAck) (*proto.Empty, error) {
+	a, c := n.handshakes.pending(in.AddrMe)
+	if a == nil {
+		return &proto.Empty{}, errors.New("no pending version from address")
+	}
+	err := n.verAck(ctx, in, c)
+	n.handshakes.complete(in.AddrMe, a, err)
+	return &proto.Empty{}, err
+}
+
+// verAck checks the initiator's signature over the challenge c and,
+// once it has answered a second challenge sent to the address it
+// claimed, adds it as an inbound peer.
+func (n *Node) verAck(ctx context.Context, in *proto.VersionAck, c *challenge) error {
+	if time.Now().After(c.expires) {
+		return errors.New("version challenge expired")
+	}
+	tlsKey, err := n.peerKey(ctx)
+	if err != nil {
+		return err
+	}
+	if tlsKey != nil && !tlsKey.Equal(c.key) {
+		n.misbehaving(ctx, scoreBadSignature, "verack key does not match connection identity")
+		return errors.New("verack key does not match connection identity")
+	}
+	if !utils.Verify(c.key, handshakeMsg(c.nonce, in.AddrMe, n.Addr), in.Sig) {
+		n.misbehaving(ctx, scoreBadSignature, "invalid version signature")
+		return errors.New("invalid version signature")
+	}
+	if err := n.dialBack(ctx, in.AddrMe, c.key); err != nil {
+		return err
+	}
+	if !n.addPeer(in.AddrMe, c.version, c.key, c.ann, true) && !n.PeerDb.In(in.AddrMe) {
+		return status.Error(codes.ResourceExhausted, "no inbound peer slots")
+	}
+	return nil
+}
+
+// DialBack signs the nonce of a node this one sent a Version to, so it
+// can check the address this node claimed.
+func (n *Node) DialBack(ctx context.Context, in *proto.DialBackRequest) (*proto.DialBackReply, error) {
+	if !n.handshakes.initiated(in.AddrMe) {
+		return &proto.DialBackReply{}, errors.New("no version sent to address")
+	}
+	if len(in.Nonce) < nonceSize {
+		return &proto.DialBackReply{}, errors.New("dial back nonce too short")
+	}
+	sig, err := utils.Sign(n.Id.PrivateKey, dialBackMsg(in.Nonce, n.Addr, in.AddrMe))
+	if err != nil {
+		return &proto.DialBackReply{}, err
+	}
+	return &proto.DialBackReply{Sig: sig}, nil
 }
 
 func (n *Node) SendAddresses(ctx context.Context, in *proto.Addresses) (*proto.Empty, error) {
-	foundNew := false
+	if n.Conf.MaxAddrsPerMsg > 0 && len(in.Addrs) > n.Conf.MaxAddrsPerMsg {
+		n.misbehaving(ctx, scoreAddrSpam, "too many addresses")
+		return &proto.Empty{}, status.Error(codes.ResourceExhausted, "too many addresses")
+	}
+	var fresh []*proto.Address
 	for _, addr := range in.Addrs {
 		if addr.Addr == n.Addr {
 			continue
 		}
-		newAddr := address.New(addr.Addr, addr.LastSeen)
-		if p := n.PeerDb.Get(addr.Addr); p != nil {
-			if p.Addr.LastSeen < addr.LastSeen {
-				err := n.PeerDb.UpdateLastSeen(addr.Addr, addr.LastSeen)
-				if err != nil {
-					fmt.Printf("ERROR {Node.SendAddresses}: error" +
-						"when calling updatelastseen.\n")
-				}
-				foundNew = true
-			}
-		} else if a := n.AddrDb.Get(addr.Addr); a != nil {
-			if a.LastSeen < addr.LastSeen {
-				err := n.AddrDb.UpdateLastSeen(addr.Addr, addr.LastSeen)
-				if err != nil {
-					fmt.Printf("ERROR {Node.SendAddresses}: error" +
-						"when calling updatelastseen.\n")
-				}
-			}
-		} else {
-			err := n.AddrDb.Add(newAddr)
-			if err == nil {
-				foundNew = true
+		if hp, err := netaddr.Parse(addr.Addr); err != nil || !hp.Dialable() {
+			continue
+		}
+		newAddr := address.Deserialize(addr)
+		if err := n.checkAddr(newAddr); err != nil {
+			if err != address.ErrUnsigned {
+				n.misbehaving(ctx, scoreBadSignature, "invalid address signature")
 			}
+			continue
 		}
-		go func() {
-			_, err := newAddr.VersionRPC(&proto.VersionRequest{
-				Version: uint32(n.Conf.Version),
-				AddrYou: newAddr.Addr,
-				AddrMe:  n.Addr,
-			})
-			if err != nil {
-				utils.Debug.Printf("%v recieved no response from VersionRPC to %v",
-					utils.FmtAddr(n.Addr), utils.FmtAddr(addr.Addr))
+		if !n.storeAddr(newAddr, remoteHost(ctx)) {
+			continue
+		}
+		fresh = append(fresh, addr)
+		select {
+		case n.gossipSem <- struct{}{}:
+		default:
+			// Enough handshakes are already in flight; the address is
+			// still recorded and may be dialed later.
+			continue
+		}
+		dial := newAddr.Addr
+		queued := n.outbound.TrySubmit(dial, func() {
+			defer func() { <-n.gossipSem }()
+			if err := n.handshake(dial); err != nil {
+				utils.Debug.Printf("%v could not complete version handshake with %v",
+					utils.FmtAddr(n.Addr), utils.FmtAddr(dial))
 			}
-		}()
+		})
+		if !queued {
+			<-n.gossipSem
+		}
 	}
-	if foundNew {
+	if len(fresh) > 0 {
 		bcPeers := n.PeerDb.GetRandom(2, []string{n.Addr})
 		for _, p := range bcPeers {
-			_, err := p.Addr.SendAddressesRPC(in)
+			_, err := p.Addr.SendAddressesRPC(ctx, n.Conns, &proto.Addresses{Addrs: fresh})
 			if err != nil {
 				utils.Debug.Printf("%v recieved no response from SendAddressesRPC to %v",
 					utils.FmtAddr(n.Addr), utils.FmtAddr(p.Addr.Addr))
@@ -110,10 +221,133 @@ func (n *Node) SendAddresses(ctx context.Context, in *proto.Addresses) (*proto.E
 	return &proto.Empty{}, nil
 }
 
-func (n *Node) GetAddresses(ctx context.Context, in *proto.Empty) (*proto.Addresses, error) {
+// checkAddr returns nil if a may be stored and relayed: it must be
+// signed by the node it describes unless unsigned records are
+// accepted for compatibility.
+func (n *Node) checkAddr(a *address.Address) error {
+	if !a.Signed() && n.Conf.AcceptUnsignedAddrs {
+		return nil
+	}
+	return a.Verify()
+}
+
+// storeAddr records an address gossiped by the node at source and
+// reports whether it was news. A signed record only replaces one with
+// an older timestamp signed by the same key, or by the key the peer at
+// the address handshook with, so only the node itself can make its
+// address look fresh.
+func (n *Node) storeAddr(a *address.Address, source string) bool {
+	p := n.PeerDb.Get(a.Addr)
+	old := n.AddrDb.Get(a.Addr)
+	// A newer record than the one a peer handshook with is kept in the
+	// address database, and must stop the same record being relayed
+	// again.
+	if p != nil && (old == nil || !old.Signed() || p.Addr.Signed() && p.Addr.Timestamp > old.Timestamp) {
+		old = p.Addr
+	}
+	if old == nil {
+		return n.addAddr(a, source) == nil
+	}
+	if a.Signed() {
+		if old.Signed() && old.Timestamp >= a.Timestamp {
+			return false
+		}
+		if p != nil && !a.SignedBy(p.PublicKey) {
+			// The peer at this address proved another key in the
+			// handshake.
+			return false
+		}
+		if p == nil && old.Signed() && a.PublicKey != old.PublicKey {
+			// Otherwise the first key to sign for the address keeps
+			// it.
+			return false
+		}
+		a.LastSeen = a.Timestamp
+		if err := n.AddrDb.Put(a); err != nil {
+			return false
+		}
+	} else if old.Signed() || old.LastSeen >= a.LastSeen {
+		return false
+	} else if p == nil {
+		if err := n.AddrDb.UpdateLastSeen(a.Addr, a.LastSeen); err != nil {
+			fmt.Printf("ERROR {Node.SendAddresses}: error" +
+				"when calling updatelastseen.\n")
+		}
+		return false
+	}
+	if p != nil {
+		if err := n.PeerDb.UpdateLastSeen(a.Addr, a.LastSeen); err != nil {
+			fmt.Printf("ERROR {Node.SendAddresses}: error" +
+				"when calling updatelastseen.\n")
+		}
+	}
+	return true
+}
+
+// addAddr adds an address heard from source to the address database,
+// letting it account for the source if it can.
+func (n *Node) addAddr(a *address.Address, source string) error {
+	if sel, ok := n.AddrDb.(addressdb.Selector); ok {
+		return sel.AddFrom(a, source)
+	}
+	return n.AddrDb.Add(a)
+}
+
+// GetAddresses answers with a random sample of the addresses last seen
+// after in.SeenAfter, so no single caller learns the node's whole view
+// of the network.
+func (n *Node) GetAddresses(ctx context.Context, in *proto.GetAddressesRequest) (*proto.Addresses, error) {
 	utils.Debug.Printf("Node {%v} received a GetAddresses req from the network.\n",
 		n.Addr)
-	return &proto.Addresses{Addrs: n.AddrDb.Serialize()}, nil
+	limit := n.Conf.AddrSampleSize
+	if in.Limit > 0 && int(in.Limit) < limit {
+		limit = int(in.Limit)
+	}
+	addrs := n.AddrDb.List()
+	rand.Shuffle(len(addrs), func(i, j int) { addrs[i], addrs[j] = addrs[j], addrs[i] })
+	sample := make([]*proto.Address, 0, limit)
+	for _, a := range addrs {
+		if len(sample) >= limit {
+			break
+		}
+		if a.LastSeen > in.SeenAfter {
+			sample = append(sample, a.Serialize())
+		}
+	}
+	return &proto.Addresses{Addrs: sample}, nil
+}
+
+// ListAddresses returns the page of addresses that follows in.Cursor
+// in address order. Only callers listed in AddrListers may use it.
+func (n *Node) ListAddresses(ctx context.Context, in *proto.ListAddressesRequest) (*proto.AddressPage, error) {
+	if !n.addrLister(n.callerID(ctx)) {
+		return &proto.AddressPage{}, status.Error(codes.PermissionDenied, "not allowed to list addresses")
+	}
+	limit := n.Conf.AddrPageSize
+	if in.Limit > 0 && int(in.Limit) < limit {
+		limit = int(in.Limit)
+	}
+	addrs := n.AddrDb.List()
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Addr < addrs[j].Addr })
+	start := sort.Search(len(addrs), func(i int) bool { return addrs[i].Addr > in.Cursor })
+	page := &proto.AddressPage{}
+	for _, a := range addrs[start:] {
+		if limit > 0 && len(page.Addrs) >= limit {
+			page.NextCursor = page.Addrs[len(page.Addrs)-1].Addr
+			break
+		}
+		page.Addrs = append(page.Addrs, a.Serialize())
+	}
+	return page, nil
+}
+
+func (n *Node) addrLister(id string) bool {
+	for _, fp := range n.Conf.AddrListers {
+		if fp == id {
+			return true
+		}
+	}
+	return false
 }
 
 func (n *Node) Register(ctx context.Context, in *proto.Registration) (*proto.Certificate, error) {
@@ -122,77 +356,145 @@ func (n *Node) Register(ctx context.Context, in *proto.Registration) (*proto.Cer
 		utils.Err.Printf("%v received error trying to make certificate",
 			utils.FmtAddr(n.Addr))
 	}
-	c := Certificate{Certificate: signa}
+	var der []byte
+	pk, err := utils.DecodePublicKey(in.Register)
+	caDER, caErr := n.Id.CACert()
+	if err == nil {
+		err = caErr
+	}
+	if err == nil {
+		der, err = id.IssueCert(n.Id.PrivateKey, caDER, pk)
+	}
+	if err != nil {
+		utils.Err.Printf("%v received error trying to issue x509 certificate",
+			utils.FmtAddr(n.Addr))
+	}
+	c := Certificate{Certificate: signa, X509: der, CAX509: caDER}
 	return c.Serialize(), nil
 }
 
-func (n *Node) AddMember(ctx context.Context, in *proto.EncKeysMem) (*proto.Empty, error) {
+// groupChange decrypts and checks the GroupChange carried by an
+// AddMember or KickMember message, penalizing the caller if it is
+// malformed or its key is not signed by the peer that sent it.
+func (n *Node) groupChange(ctx context.Context, in *proto.EncKeysMem) (*GroupChange, error) {
 	stuff, err := utils.PubDecrypt(n.Id.PrivateKey, in.Encryptedstuff)
 	if err != nil {
-		utils.Err.Printf("%v received error trying to decrypt add member message",
-			utils.FmtAddr(n.Addr))
+		n.misbehaving(ctx, scoreUndecryptable, "undecryptable group change")
+		return nil, err
 	}
 	gc, err := GCDeserialize(stuff)
 	if err != nil {
-		utils.Err.Printf("%v received error trying to decode add member message",
-			utils.FmtAddr(n.Addr))
+		n.misbehaving(ctx, scoreMalformed, "malformed group change")
+		return nil, err
+	}
+	if len(gc.Members) == 0 {
+		n.misbehaving(ctx, scoreMalformed, "group change without members")
+		return nil, errors.New("group change without members")
 	}
-	m := make(map[string]bool)
-	for _, item := range n.Group.Members {
-		m[item.Addr.Addr] = true
+	if err := n.peerCaller(ctx, gc.Sender); err != nil {
+		return nil, err
 	}
-	var diff []string
+	sender := n.PeerDb.Get(gc.Sender)
+	if sender == nil || !utils.Verify(sender.PublicKey, gc.Key, gc.SigOverKey) {
+		n.misbehaving(ctx, scoreBadSignature, "bad signature over group key")
+		return nil, errors.New("bad signature over group key")
+	}
+	return gc, nil
+}
+
+func (n *Node) AddMember(ctx context.Context, in *proto.EncKeysMem) (*proto.Empty, error) {
+	gc, err := n.groupChange(ctx, in)
+	if err != nil {
+		utils.Err.Printf("%v received invalid add member message",
+			utils.FmtAddr(n.Addr))
+		return &proto.Empty{}, err
+	}
+	self := n.Fingerprint()
+	var diff []group.Member
 	for _, item := range gc.Members {
-		if _, ok := m[item]; !ok && item != n.Addr {
+		if item.ID != self && n.Group.Get(item.ID) == nil {
 			diff = append(diff, item)
 		}
 	}
 	for _, mem := range diff {
-		if mem != n.Addr {
-			n.ConnectToPeer(mem)
+		if n.peerByID(mem.ID) == nil {
+			mem := mem
+			queued := n.outbound.TrySubmit(mem.Addr, func() { n.connectToMember(mem) })
+			if !queued {
+				utils.Debug.Printf("%v dropped the connection to new member %v",
+					utils.FmtAddr(n.Addr), utils.FmtAddr(mem.Addr))
+			}
 		}
 	}
-	time.Sleep(1 * time.Second)
+	// New members may be dialing this node at the same time, so wait
+	// for a handshake from either side.
+	wctx, cancel := context.WithTimeout(ctx, n.Conf.VerTimeout)
+	defer cancel()
 	for _, mem := range diff {
-		for _, p := range n.PeerDb.List() {
-			if p.Addr.Addr == mem {
-				n.Group.AddMember(p)
-			}
+		if p := n.waitForID(wctx, mem.ID); p != nil {
+			n.Group.AddMember(p)
 		}
 	}
 	n.Group.ReplaceKeys(gc.Key)
 	for _, mem := range diff {
 		utils.Debug.Printf("%v added %v",
-			utils.FmtAddr(n.Addr), utils.FmtAddr(mem))
+			utils.FmtAddr(n.Addr), utils.FmtAddr(mem.Addr))
 	}
 	return &proto.Empty{}, nil
 }
 
 func (n *Node) KickMember(ctx context.Context, in *proto.EncKeysMem) (*proto.Empty, error) {
-	stuff, err := utils.PubDecrypt(n.Id.PrivateKey, in.Encryptedstuff)
-	if err != nil {
-		utils.Err.Printf("%v received error trying to decrypt kick member message",
-			utils.FmtAddr(n.Addr))
-	}
-	gc, err := GCDeserialize(stuff)
+	gc, err := n.groupChange(ctx, in)
 	if err != nil {
-		utils.Err.Printf("%v received error trying to decode kick member message",
+		utils.Err.Printf("%v received invalid kick member message",
 			utils.FmtAddr(n.Addr))
+		return &proto.Empty{}, err
 	}
-	n.Group.KickMyMember(gc.Members[0])
+	n.Group.KickMyMember(gc.Members[0].ID)
 	n.Group.ReplaceKeys(gc.Key)
 	utils.Debug.Printf("%v received kick msg and kicked %v",
-		utils.FmtAddr(n.Addr), utils.FmtAddr(gc.Members[0]))
+		utils.FmtAddr(n.Addr), utils.FmtAddr(gc.Members[0].Addr))
 	return &proto.Empty{}, nil
 }
 
 func (n *Node) GroupMessage(ctx context.Context, in *proto.GroupIM) (*proto.Empty, error) {
-	plain, err := utils.SymDecrypt(n.Group.GCM, in.Encryptedmsg)
+	plain, err := n.Group.Decrypt(in.Encryptedmsg)
+	if err == group.ErrNoGroup {
+		return &proto.Empty{}, err
+	}
 	if err != nil {
 		utils.Err.Printf("%v received error trying to decrypt message",
 			utils.FmtAddr(n.Addr))
+		n.misbehaving(ctx, scoreBadGroupMessage, "undecryptable group message")
+		return &proto.Empty{}, err
 	}
 	utils.Debug.Printf("%v received message %v",
 		utils.FmtAddr(n.Addr), plain)
 	return &proto.Empty{}, nil
 }
+
+// Ping answers a probe from a peer, which shows the peer is alive too.
+func (n *Node) Ping(ctx context.Context, in *proto.PingRequest) (*proto.Ack, error) {
+	if err := n.peerCaller(ctx, in.AddrMe); err != nil {
+		return &proto.Ack{}, err
+	}
+	n.swim.Alive(in.AddrMe)
+	return &proto.Ack{Seq: in.Seq}, nil
+}
+
+// PingReq probes a peer on behalf of another. Other callers and
+// targets are refused so the node cannot be used to flood arbitrary
+// addresses.
+func (n *Node) PingReq(ctx context.Context, in *proto.PingReqRequest) (*proto.Ack, error) {
+	if err := n.peerCaller(ctx, in.AddrMe); err != nil {
+		return &proto.Ack{}, err
+	}
+	if !n.PeerDb.In(in.Target) {
+		return &proto.Ack{}, errors.New("ping target is not a peer")
+	}
+	target := address.New(in.Target, 0)
+	if _, err := target.PingRPC(ctx, n.Conns, &proto.PingRequest{AddrMe: n.Addr, Seq: in.Seq}); err != nil {
+		return &proto.Ack{}, err
+	}
+	return &proto.Ack{Seq: in.Seq}, nil
+}
@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/swim"
+	"finalbruh/pkg/utils"
+	"sync"
+	"time"
+)
+
+// StaticPeerStatus reports on a peer from Config.StaticPeers.
+type StaticPeerStatus struct {
+	Addr      string
+	Connected bool
+	// Failures counts the attempts that failed since the last success,
+	// the latest with LastError.
+	Failures    int
+	LastError   string
+	LastAttempt time.Time
+	NextAttempt time.Time
+}
+
+// staticPeers holds the status of the static peers and wakes their
+// reconnection loops.
+type staticPeers struct {
+	status map[string]*StaticPeerStatus
+	wake   map[string]chan struct{}
+	sync.Mutex
+}
+
+func newStaticPeers(addrs []string) *staticPeers {
+	sp := &staticPeers{
+		status: make(map[string]*StaticPeerStatus),
+		wake:   make(map[string]chan struct{}),
+	}
+	for _, addr := range addrs {
+		sp.status[addr] = &StaticPeerStatus{Addr: addr}
+		sp.wake[addr] = make(chan struct{}, 1)
+	}
+	return sp
+}
+
+// has reports whether addr is a static peer.
+func (sp *staticPeers) has(addr string) bool {
+	sp.Lock()
+	defer sp.Unlock()
+	return sp.status[addr] != nil
+}
+
+// kick wakes the loop for addr, or every loop if addr is empty.
+func (sp *staticPeers) kick(addr string) {
+	sp.Lock()
+	defer sp.Unlock()
+	for a, ch := range sp.wake {
+		if addr != "" && a != addr {
+			continue
+		}
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (sp *staticPeers) record(addr string, err error, next time.Time) {
+	sp.Lock()
+	defer sp.Unlock()
+	s := sp.status[addr]
+	s.LastAttempt = time.Now()
+	s.NextAttempt = next
+	s.Connected = err == nil
+	if err != nil {
+		s.Failures++
+		s.LastError = err.Error()
+	} else {
+		s.Failures = 0
+		s.LastError = ""
+	}
+}
+
+// StaticPeers returns the status of the peers the node keeps connected
+// to.
+func (n *Node) StaticPeers() []StaticPeerStatus {
+	n.static.Lock()
+	defer n.static.Unlock()
+	var status []StaticPeerStatus
+	for _, addr := range n.Conf.StaticPeers {
+		if s := n.static.status[addr]; s != nil {
+			st := *s
+			st.Connected = st.Connected && n.PeerDb.In(addr)
+			status = append(status, st)
+		}
+	}
+	return status
+}
+
+// keepStaticPeers starts a loop for each static peer that connects to
+// it and reconnects whenever it is lost, until the node shuts down.
+func (n *Node) keepStaticPeers() {
+	if len(n.Conf.StaticPeers) == 0 {
+		return
+	}
+	for _, addr := range n.Conf.StaticPeers {
+		addr := addr
+		n.spawn(&n.workers, func() { n.keepConnected(addr) })
+	}
+	// Reconnect as soon as the failure detector gives up on a static
+	// peer rather than at its next refresh.
+	events := n.swim.Subscribe()
+	n.spawn(&n.workers, func() {
+		for {
+			select {
+			case <-n.ctx.Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				if ev.State == swim.Dead {
+					n.static.kick(ev.Addr)
+				}
+			}
+		}
+	})
+}
+
+// keepConnected runs the Version handshake with addr, again every
+// StaticRefreshInterval while it succeeds so a restarted peer learns
+// of the node, and after a backoff doubling from StaticRetryMin up to
+// StaticRetryMax while it fails.
+func (n *Node) keepConnected(addr string) {
+	n.static.Lock()
+	wake := n.static.wake[addr]
+	n.static.Unlock()
+	retry := address.RetryPolicy{Backoff: n.Conf.StaticRetryMin, MaxBackoff: n.Conf.StaticRetryMax}
+	var delay time.Duration
+	failures := 0
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-n.ctx.Done():
+			timer.Stop()
+			return
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+		err := n.handshake(addr)
+		if err == nil {
+			failures = 0
+			delay = n.Conf.StaticRefreshInterval
+		} else {
+			failures++
+			delay = retry.Delay(failures)
+			utils.Debug.Printf("%v could not reach static peer %v, retrying in %v",
+				utils.FmtAddr(n.Addr), utils.FmtAddr(addr), delay)
+		}
+		n.static.record(addr, err, time.Now().Add(delay))
+	}
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Save atomically replaces the file at path with v encoded as JSON. It
+// writes and syncs a temporary file, then renames it over path, so a
+// crash at any point leaves either the old or the new contents.
+func Save(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// Sync the directory so the rename itself survives a crash.
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Load decodes the file at path into v. A missing file leaves v as it
+// is.
+func Load(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Persister saves snapshots of some state to a file in the background.
+// Changes reported through Dirty within one interval are written
+// together.
+type Persister struct {
+	path     string
+	interval time.Duration
+	snapshot func() interface{}
+
+	dirty   chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	discard bool
+	err     error
+}
+
+// NewPersister saves the value returned by snapshot to path after
+// every change. snapshot is called from the persister's goroutine.
+func NewPersister(path string, interval time.Duration, snapshot func() interface{}) *Persister {
+	p := &Persister{
+		path:     path,
+		interval: interval,
+		snapshot: snapshot,
+		dirty:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Dirty reports that the state changed and must be saved.
+func (p *Persister) Dirty() {
+	select {
+	case p.dirty <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Persister) run() {
+	defer close(p.done)
+	for {
+		select {
+		case <-p.dirty:
+			t := time.NewTimer(p.interval)
+			select {
+			case <-t.C:
+			case <-p.stop:
+				t.Stop()
+				if p.discard {
+					return
+				}
+			}
+			p.err = Save(p.path, p.snapshot())
+		case <-p.stop:
+			if !p.discard {
+				p.err = Save(p.path, p.snapshot())
+			}
+			return
+		}
+	}
+}
+
+// Close saves the state a last time and returns the error of the last
+// save.
+func (p *Persister) Close() error {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+	return p.err
+}
+
+// Discard stops the persister without saving changes not yet written.
+func (p *Persister) Discard() {
+	p.once.Do(func() {
+		p.discard = true
+		close(p.stop)
+	})
+	<-p.done
+}
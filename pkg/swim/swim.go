@@ -0,0 +1,300 @@
+package swim
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	Alive State = iota
+	Suspect
+	Dead
+)
+
+func (s State) String() string {
+	switch s {
+	case Alive:
+		return "alive"
+	case Suspect:
+		return "suspect"
+	default:
+		return "dead"
+	}
+}
+
+// Event reports that the member at Addr changed to State.
+type Event struct {
+	Addr  string
+	State State
+}
+
+// Prober sends the probes of the protocol. Ping asks addr directly
+// for an ack; PingReq asks via to ping target and relay the ack. Both
+// give up when ctx is done.
+type Prober interface {
+	Ping(ctx context.Context, addr string) error
+	PingReq(ctx context.Context, via, target string) error
+}
+
+type Config struct {
+	// Interval is the protocol period: one member is probed per
+	// period.
+	Interval time.Duration
+	// Timeout bounds how long a direct or indirect probe may take.
+	Timeout time.Duration
+	// Indirect is how many other members are asked to probe a member
+	// that failed to answer a direct ping.
+	Indirect int
+	// Suspicion is how long a member stays suspect before it is
+	// confirmed dead.
+	Suspicion time.Duration
+}
+
+type member struct {
+	state   State
+	suspect time.Time
+}
+
+var errTimeout = errors.New("probe timed out")
+
+// Detector is a SWIM-style failure detector. Every period it pings the
+// next member in a shuffled round-robin order; if the ping fails it
+// asks Indirect other members to ping it, and if those fail too the
+// member becomes suspect. A suspect that does not answer a probe
+// within the suspicion timeout is declared dead and forgotten.
+type Detector struct {
+	conf    Config
+	prober  Prober
+	members func() []string
+
+	state map[string]*member
+	order []string
+	subs  []chan Event
+
+	stop    chan struct{}
+	done    chan struct{}
+	stopped bool
+	sync.Mutex
+}
+
+// New returns a detector for the members listed by members, which is
+// called at the start of every round.
+func New(conf Config, prober Prober, members func() []string) *Detector {
+	return &Detector{
+		conf:    conf,
+		prober:  prober,
+		members: members,
+		state:   make(map[string]*member),
+	}
+}
+
+// Start runs the protocol in the background until Stop is called.
+func (d *Detector) Start() {
+	d.Lock()
+	defer d.Unlock()
+	if d.stop != nil || d.stopped {
+		return
+	}
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go d.run(d.stop, d.done)
+}
+
+// Stop halts the protocol, waits for the current probe to finish and
+// closes every subscriber channel. A stopped detector cannot be
+// restarted.
+func (d *Detector) Stop() {
+	d.Lock()
+	if d.stopped {
+		d.Unlock()
+		return
+	}
+	d.stopped = true
+	stop, done := d.stop, d.done
+	d.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	d.Lock()
+	defer d.Unlock()
+	for _, ch := range d.subs {
+		close(ch)
+	}
+	d.subs = nil
+}
+
+// Subscribe returns a channel receiving every state change. Events are
+// dropped for subscribers that fall more than 64 events behind.
+func (d *Detector) Subscribe() <-chan Event {
+	d.Lock()
+	defer d.Unlock()
+	ch := make(chan Event, 64)
+	if d.stopped {
+		close(ch)
+		return ch
+	}
+	d.subs = append(d.subs, ch)
+	return ch
+}
+
+// State returns the last known state of addr. Unknown members are
+// reported alive.
+func (d *Detector) State(addr string) State {
+	d.Lock()
+	defer d.Unlock()
+	if m := d.state[addr]; m != nil {
+		return m.state
+	}
+	return Alive
+}
+
+// Alive records evidence outside of probing that addr is alive, such
+// as an RPC received from it, refuting any suspicion.
+func (d *Detector) Alive(addr string) {
+	d.set(addr, Alive)
+}
+
+func (d *Detector) run(stop, done chan struct{}) {
+	defer close(done)
+	// An interval of 0 disables probing.
+	var ticks <-chan time.Time
+	if d.conf.Interval > 0 {
+		ticker := time.NewTicker(d.conf.Interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticks:
+			d.tick()
+		}
+	}
+}
+
+// tick probes the next member and expires suspicions.
+func (d *Detector) tick() {
+	if target := d.next(); target != "" {
+		if d.probe(target) {
+			d.set(target, Alive)
+		} else {
+			d.set(target, Suspect)
+		}
+	}
+	now := time.Now()
+	d.Lock()
+	var dead []string
+	for addr, m := range d.state {
+		if m.state == Suspect && now.Sub(m.suspect) >= d.conf.Suspicion {
+			dead = append(dead, addr)
+		}
+	}
+	d.Unlock()
+	for _, addr := range dead {
+		d.set(addr, Dead)
+	}
+}
+
+// next returns the member to probe this period, reshuffling the
+// member list once every member has been probed and forgetting
+// members that are no longer listed.
+func (d *Detector) next() string {
+	d.Lock()
+	defer d.Unlock()
+	if len(d.order) == 0 {
+		d.order = d.members()
+		current := make(map[string]bool, len(d.order))
+		for _, addr := range d.order {
+			current[addr] = true
+		}
+		for addr := range d.state {
+			if !current[addr] {
+				delete(d.state, addr)
+			}
+		}
+		rand.Shuffle(len(d.order), func(i, j int) {
+			d.order[i], d.order[j] = d.order[j], d.order[i]
+		})
+	}
+	if len(d.order) == 0 {
+		return ""
+	}
+	target := d.order[0]
+	d.order = d.order[1:]
+	return target
+}
+
+// probe pings target directly and then indirectly, reporting whether
+// any ack arrived in time.
+func (d *Detector) probe(target string) bool {
+	if d.withTimeout(func(ctx context.Context) error { return d.prober.Ping(ctx, target) }) == nil {
+		return true
+	}
+	var helpers []string
+	for _, addr := range d.members() {
+		if addr != target && d.State(addr) == Alive {
+			helpers = append(helpers, addr)
+		}
+	}
+	rand.Shuffle(len(helpers), func(i, j int) { helpers[i], helpers[j] = helpers[j], helpers[i] })
+	if len(helpers) > d.conf.Indirect {
+		helpers = helpers[:d.conf.Indirect]
+	}
+	acks := make(chan error, len(helpers))
+	for _, via := range helpers {
+		go func(via string) {
+			acks <- d.withTimeout(func(ctx context.Context) error { return d.prober.PingReq(ctx, via, target) })
+		}(via)
+	}
+	for range helpers {
+		if <-acks == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Detector) withTimeout(f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.conf.Timeout)
+	defer cancel()
+	err := f(ctx)
+	if err != nil && ctx.Err() != nil {
+		return errTimeout
+	}
+	return err
+}
+
+// set moves addr to state and notifies subscribers of a change. Dead
+// members are forgotten so that they start afresh if they return.
+func (d *Detector) set(addr string, state State) {
+	d.Lock()
+	defer d.Unlock()
+	m := d.state[addr]
+	if m == nil {
+		m = &member{state: Alive}
+		d.state[addr] = m
+	}
+	if m.state == state {
+		return
+	}
+	if state == Suspect {
+		m.suspect = time.Now()
+	}
+	m.state = state
+	if state == Dead {
+		delete(d.state, addr)
+	}
+	for _, ch := range d.subs {
+		select {
+		case ch <- Event{Addr: addr, State: state}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"errors"
+	"finalbruh/pkg/netaddr"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"sync"
+)
+
+const memBufSize = 1 << 20
+
+// Memory is an in-process network for tests. Every node lives on one
+// virtual host, so listeners are told apart by port only and the host
+// part of an address is ignored. Port 0 allocates the next free port.
+type Memory struct {
+	listeners map[int]*memListener
+	nextPort  int
+	sync.Mutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{listeners: make(map[int]*memListener), nextPort: 1}
+}
+
+func (m *Memory) Listen(addr string) (net.Listener, error) {
+	hp, err := netaddr.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	m.Lock()
+	defer m.Unlock()
+	if hp.Port == 0 {
+		for m.listeners[m.nextPort] != nil {
+			m.nextPort++
+		}
+		hp.Port = m.nextPort
+		m.nextPort++
+	}
+	if m.listeners[hp.Port] != nil {
+		return nil, errors.New("address already in use: " + addr)
+	}
+	l := &memListener{Listener: bufconn.Listen(memBufSize), mem: m, addr: memAddr(hp.String())}
+	m.listeners[hp.Port] = l
+	return l, nil
+}
+
+func (m *Memory) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	hp, err := netaddr.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	m.Lock()
+	l := m.listeners[hp.Port]
+	m.Unlock()
+	if l == nil {
+		return nil, errors.New("connection refused: " + addr)
+	}
+	return l.DialContext(ctx)
+}
+
+type memListener struct {
+	*bufconn.Listener
+	mem  *Memory
+	addr memAddr
+}
+
+func (l *memListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *memListener) Close() error {
+	hp, _ := netaddr.Parse(string(l.addr))
+	l.mem.Lock()
+	if l.mem.listeners[hp.Port] == l {
+		delete(l.mem.listeners, hp.Port)
+	}
+	l.mem.Unlock()
+	return l.Listener.Close()
+}
+
+type memAddr string
+
+func (memAddr) Network() string  { return "mem" }
+func (a memAddr) String() string { return string(a) }
@@ -0,0 +1,25 @@
+package transport
+
+import (
+	"golang.org/x/net/context"
+	"net"
+)
+
+// Transport creates the listeners a node serves RPCs on and the
+// connections it dials peers with.
+type Transport interface {
+	Listen(addr string) (net.Listener, error)
+	Dial(ctx context.Context, addr string) (net.Conn, error)
+}
+
+// TCP is the transport used outside of tests.
+type TCP struct{}
+
+func (TCP) Listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+func (TCP) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
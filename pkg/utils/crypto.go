@@ -22,6 +22,14 @@ func Hash(v []byte) string {
 	return hex.EncodeToString(h[:])
 }
 
+func RandomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
 func GenerateSymKey() (string, cipher.AEAD, error) {
 	key := make([]byte, 32)
 	if _, err := rand.Read(key); err != nil {
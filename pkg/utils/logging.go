@@ -1,12 +1,11 @@
 package utils
 
 import (
+	"finalbruh/pkg/netaddr"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
-	"strconv"
-	"strings"
 )
 
 var Debug *log.Logger
@@ -34,8 +33,8 @@ func FmtAddr(addr string) string {
 		return ""
 	}
 	colors := []string{"\033[41m", "\033[42m", "\033[43m", "\033[44m", "\033[45m", "\033[46m", "\033[47m"}
-	port, _ := strconv.ParseInt(strings.Split(addr, ":")[1], 10, 64)
-	randomColor := colors[int(port)%len(colors)]
+	hp, _ := netaddr.Parse(addr)
+	randomColor := colors[hp.Port%len(colors)]
 	return fmt.Sprintf("%v\033[97m[%v]\033[0m", randomColor, addr)
 }
 
@@ -0,0 +1,207 @@
+package address
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/transport"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func insecureConfig() *pkg.Config {
+	c := pkg.DefaultConfig(test.GetFreePort())
+	c.Insecure = true
+	return c
+}
+
+func TestConnectionReuse(t *testing.T) {
+	node1 := pkg.New(insecureConfig())
+	node2 := pkg.New(insecureConfig())
+	test.Start(t, node1)
+	test.Start(t, node2)
+	defer node1.Kill()
+	defer node2.Kill()
+
+	cm := address.NewConnManager(1, 0, nil)
+	defer cm.Close()
+
+	a1 := address.New(node1.Addr, 0)
+	for i := 0; i < 5; i++ {
+		if _, err := a1.GetAddressesRPC(context.Background(), cm, &proto.GetAddressesRequest{}); err != nil {
+			t.Fatalf("GetAddressesRPC failed: %v", err)
+		}
+	}
+	if cm.Len() != 1 {
+		t.Errorf("Expected 1 cached connection, got %v", cm.Len())
+	}
+
+	a2 := address.New(node2.Addr, 0)
+	if _, err := a2.GetAddressesRPC(context.Background(), cm, &proto.GetAddressesRequest{}); err != nil {
+		t.Fatalf("GetAddressesRPC failed: %v", err)
+	}
+	if cm.Len() != 1 {
+		t.Errorf("Expected connection limit to evict, got %v connections", cm.Len())
+	}
+}
+
+func TestIdleEviction(t *testing.T) {
+	node := pkg.New(insecureConfig())
+	test.Start(t, node)
+	defer node.Kill()
+
+	cm := address.NewConnManager(10, 100*time.Millisecond, nil)
+	defer cm.Close()
+
+	a := address.New(node.Addr, 0)
+	if _, err := a.GetAddressesRPC(context.Background(), cm, &proto.GetAddressesRequest{}); err != nil {
+		t.Fatalf("GetAddressesRPC failed: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if cm.Len() != 0 {
+		t.Errorf("Expected idle connection to be closed, got %v", cm.Len())
+	}
+}
+
+func TestClosedManager(t *testing.T) {
+	cm := address.NewConnManager(10, 0, nil)
+	if err := cm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	a := address.New("localhost:1", 0)
+	if _, err := a.GetAddressesRPC(context.Background(), cm, &proto.GetAddressesRequest{}); err == nil {
+		t.Errorf("Expected error from closed connection manager")
+	}
+}
+
+// stalled never answers GroupMessage and counts how often it was
+// called.
+type stalled struct {
+	*proto.UnimplementedBrunoCoinServer
+	calls int32
+}
+
+func (s *stalled) GroupMessage(ctx context.Context, in *proto.GroupIM) (*proto.Empty, error) {
+	atomic.AddInt32(&s.calls, 1)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestDeadlines(t *testing.T) {
+	mem := transport.NewMemory()
+	lis, err := mem.Listen("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &stalled{}
+	s := grpc.NewServer()
+	proto.RegisterBrunoCoinServer(s, srv)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	cm := address.NewConnManager(10, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	a := address.New(lis.Addr().String(), 0)
+	call := func(ctx context.Context) (time.Duration, error) {
+		start := time.Now()
+		_, err := a.GroupMessageRPC(ctx, cm, &proto.GroupIM{})
+		return time.Since(start), err
+	}
+
+	// A caller cancelling gets control back at once, without retries.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if took, err := call(ctx); status.Code(err) != codes.Canceled || took > time.Second {
+		t.Errorf("Cancelled call returned %v after %v", err, took)
+	}
+	if n := atomic.LoadInt32(&srv.calls); n != 1 {
+		t.Errorf("Cancelled call was attempted %v times", n)
+	}
+
+	// A caller's deadline bounds all attempts together.
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if took, err := call(ctx); status.Code(err) != codes.DeadlineExceeded || took > time.Second {
+		t.Errorf("Call past the caller's deadline returned %v after %v", err, took)
+	}
+
+	// A per-method timeout overrides the default one for each attempt.
+	cm.SetRetryPolicies(nil)
+	cm.SetTimeouts(time.Minute, map[string]time.Duration{"/BrunoCoin/GroupMessage": 100 * time.Millisecond})
+	if took, err := call(context.Background()); status.Code(err) != codes.DeadlineExceeded || took > time.Second {
+		t.Errorf("Call past its method's timeout returned %v after %v", err, took)
+	}
+}
+
+// held answers GroupMessage once release is closed.
+type held struct {
+	*proto.UnimplementedBrunoCoinServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *held) GroupMessage(ctx context.Context, in *proto.GroupIM) (*proto.Empty, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return &proto.Empty{}, nil
+}
+
+func TestBusyConnectionsStayOpen(t *testing.T) {
+	mem := transport.NewMemory()
+	var addrs []string
+	srv := &held{
+		UnimplementedBrunoCoinServer: &proto.UnimplementedBrunoCoinServer{},
+		started:                      make(chan struct{}, 2),
+		release:                      make(chan struct{}),
+	}
+	for i := 0; i < 2; i++ {
+		lis, err := mem.Listen("localhost:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := grpc.NewServer()
+		proto.RegisterBrunoCoinServer(s, srv)
+		go s.Serve(lis)
+		defer s.Stop()
+		addrs = append(addrs, lis.Addr().String())
+	}
+
+	cm := address.NewConnManager(1, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	cm.SetRetryPolicies(nil)
+	errs := make(chan error, 2)
+	for _, addr := range addrs {
+		a := address.New(addr, 0)
+		go func() {
+			_, err := a.GroupMessageRPC(context.Background(), cm, &proto.GroupIM{})
+			errs <- err
+		}()
+		<-srv.started
+	}
+	// The second connection went over the limit rather than closing
+	// the first, which is busy; dropping them waits for their RPCs.
+	if cm.Len() != 2 {
+		t.Errorf("Expected both busy connections open, got %v", cm.Len())
+	}
+	cm.Drop(addrs[0])
+	cm.Drop(addrs[1])
+	close(srv.release)
+	for range addrs {
+		if err := <-errs; err != nil {
+			t.Fatalf("RPC on an evicted or dropped connection failed: %v", err)
+		}
+	}
+	if cm.Len() != 0 {
+		t.Errorf("Expected dropped connections to be forgotten, got %v", cm.Len())
+	}
+	// A client from before the drop dials again.
+	if _, err := address.New(addrs[0], 0).GetAddressesRPC(context.Background(), cm, &proto.GetAddressesRequest{}); status.Code(err) != codes.Unimplemented {
+		t.Fatalf("RPC after a drop returned %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package addrman
+
+import (
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/address/addressdb"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var _ addressdb.AddressDb = addressdb.NewAddrMan(10)
+
+func TestSingleSourceCannotFillTable(t *testing.T) {
+	am := addressdb.NewAddrManSeeded(1000, 1)
+	// An attacker at one address announces addresses spread over many
+	// network groups.
+	for i := 0; i < 5000; i++ {
+		addr := fmt.Sprintf("10.%d.%d.1:8000", i/256, i%256)
+		_ = am.AddFrom(address.New(addr, 0), "6.6.6.6:1")
+	}
+	flooded := len(am.List())
+	if flooded > 64 {
+		t.Fatalf("One source placed %v addresses", flooded)
+	}
+	// Honest sources in other groups still get their addresses in.
+	added := 0
+	for i := 0; i < 50; i++ {
+		src := fmt.Sprintf("%d.1.1.1:1", 20+i)
+		if am.AddFrom(address.New(fmt.Sprintf("%d.2.2.2:8000", 20+i), 0), src) == nil {
+			added++
+		}
+	}
+	if added < 35 {
+		t.Fatalf("Only %v of 50 honest addresses were added", added)
+	}
+}
+
+func TestAddressDbBehavior(t *testing.T) {
+	am := addressdb.NewAddrMan(100)
+	if err := am.Add(address.New("1.2.3.4:1", 5)); err != nil {
+		t.Fatal(err)
+	}
+	if am.Add(address.New("1.2.3.4:1", 6)) == nil {
+		t.Errorf("Added a duplicate address")
+	}
+	if err := am.UpdateLastSeen("1.2.3.4:1", 9); err != nil || am.Get("1.2.3.4:1").LastSeen != 9 {
+		t.Errorf("LastSeen not updated")
+	}
+	if am.UpdateLastSeen("9.9.9.9:1", 1) == nil {
+		t.Errorf("Updated an unknown address")
+	}
+	if err := am.Put(address.New("1.2.3.4:1", 11)); err != nil || am.Get("1.2.3.4:1").LastSeen != 11 {
+		t.Errorf("Put did not replace the record")
+	}
+	if len(am.Serialize()) != 1 {
+		t.Errorf("Serialize returned %v records, want 1", len(am.Serialize()))
+	}
+}
+
+// newAddrMan returns an address manager holding addrs. Its seed is
+// one under which none of them collide.
+func newAddrMan(t *testing.T, addrs []string) *addressdb.AddrMan {
+	am := addressdb.NewAddrManSeeded(1000, 1)
+	for _, a := range addrs {
+		if err := am.AddFrom(address.New(a, 0), a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return am
+}
+
+func TestSelectPrefersDiverseAndTried(t *testing.T) {
+	am := newAddrMan(t, []string{"1.1.1.1:1", "1.1.2.2:1", "1.1.3.3:1", "2.2.2.2:1"})
+	for i := 0; i < 20; i++ {
+		sel := am.Select(2, nil)
+		if len(sel) != 2 {
+			t.Fatalf("Selected %v addresses, want 2", len(sel))
+		}
+		if (sel[0].Addr == "2.2.2.2:1") == (sel[1].Addr == "2.2.2.2:1") {
+			t.Fatalf("Selected two addresses from one group: %v %v", sel[0].Addr, sel[1].Addr)
+		}
+	}
+	// With 1.1.0.0/16 excluded, 2.2.2.2 always comes first and the rest
+	// only fill up.
+	if sel := am.Select(4, []string{"1.1.9.9:1"}); len(sel) != 4 || sel[0].Addr != "2.2.2.2:1" {
+		t.Fatalf("Excluded group was not avoided")
+	}
+
+	// The single tried address is picked first about half the time,
+	// far more often than any one of the new ones.
+	am.Good("1.1.2.2:1")
+	first := 0
+	for i := 0; i < 400; i++ {
+		if am.Select(1, nil)[0].Addr == "1.1.2.2:1" {
+			first++
+		}
+	}
+	if first < 150 {
+		t.Fatalf("Tried address picked first %v of 400 times", first)
+	}
+}
+
+func TestEvictsLeastRecentlyHeard(t *testing.T) {
+	// The seed is one under which the five addresses do not collide.
+	am := addressdb.NewAddrManSeeded(3, 1)
+	for _, a := range []string{"1.1.1.1:1", "2.2.2.2:1", "3.3.3.3:1"} {
+		if err := am.Add(address.New(a, 0)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Hearing of 1.1.1.1 again leaves 2.2.2.2 the stalest.
+	_ = am.UpdateLastSeen("1.1.1.1:1", 1)
+	if err := am.Add(address.New("4.4.4.4:1", 0)); err != nil {
+		t.Fatalf("Full database refused a new address: %v", err)
+	}
+	if len(am.List()) != 3 {
+		t.Fatalf("Database holds %v addresses, want 3", len(am.List()))
+	}
+	if am.Get("2.2.2.2:1") != nil || am.Get("1.1.1.1:1") == nil {
+		t.Fatalf("Evicted the wrong address")
+	}
+	// A tried address is kept even when it is the stalest.
+	am.Good("3.3.3.3:1")
+	time.Sleep(time.Millisecond)
+	_ = am.UpdateLastSeen("1.1.1.1:1", 2)
+	_ = am.UpdateLastSeen("4.4.4.4:1", 2)
+	if err := am.Add(address.New("5.5.5.5:1", 0)); err != nil {
+		t.Fatalf("Full database refused a new address: %v", err)
+	}
+	if am.Get("3.3.3.3:1") == nil || am.Get("1.1.1.1:1") != nil {
+		t.Fatalf("Evicted the wrong address")
+	}
+}
+
+func TestEvictsTerribleFirst(t *testing.T) {
+	am := addressdb.NewAddrManSeeded(3, 1)
+	for _, a := range []string{"2.2.2.2:1", "3.3.3.3:1"} {
+		if err := am.Add(address.New(a, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(time.Millisecond)
+	// Heard of last, but announced by its node long ago.
+	stale := address.New("1.1.1.1:1", 0)
+	stale.Sig = "sig"
+	stale.Timestamp = time.Now().Add(-60 * 24 * time.Hour).UnixNano()
+	if err := am.Add(stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.Add(address.New("4.4.4.4:1", 0)); err != nil {
+		t.Fatalf("Full database refused a new address: %v", err)
+	}
+	if am.Get("1.1.1.1:1") != nil || am.Get("2.2.2.2:1") == nil {
+		t.Fatalf("Kept a terrible address over the least recently heard one")
+	}
+}
+
+// withStale returns a database holding 1.1.1.1 and 2.2.2.2, heard of
+// at least 20ms ago, and 3.3.3.3, heard of just now.
+func withStale(t *testing.T, adb addressdb.AddressDb) addressdb.AddressDb {
+	for _, a := range []string{"1.1.1.1:1", "2.2.2.2:1"} {
+		if err := adb.Add(address.New(a, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := adb.Add(address.New("3.3.3.3:1", 0)); err != nil {
+		t.Fatal(err)
+	}
+	return adb
+}
+
+func TestPrune(t *testing.T) {
+	adb := withStale(t, addressdb.NewAddrManSeeded(100, 1))
+	keep := func(addr string) bool { return addr == "2.2.2.2:1" }
+	if pruned := adb.Prune(10*time.Millisecond, keep); pruned != 1 {
+		t.Fatalf("Pruned %v addresses, want 1", pruned)
+	}
+	if adb.Get("1.1.1.1:1") != nil || adb.Get("2.2.2.2:1") == nil || adb.Get("3.3.3.3:1") == nil {
+		t.Fatalf("Pruned the wrong addresses")
+	}
+}
@@ -0,0 +1,232 @@
+package ban
+
+import (
+	"crypto/rsa"
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/group"
+	"finalbruh/pkg/id"
+	"finalbruh/pkg/peer"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/transport"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"fmt"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func memNodes(t *testing.T, n int) []*pkg.Node {
+	mem := transport.NewMemory()
+	nodes := make([]*pkg.Node, n)
+	for i := range nodes {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		nodes[i] = pkg.New(c)
+		test.Start(t, nodes[i])
+	}
+	return nodes
+}
+
+func TestMisbehaviorBan(t *testing.T) {
+	nodes := memNodes(t, 2)
+	node, attacker := nodes[0], nodes[1]
+	defer node.Kill()
+	defer attacker.Kill()
+
+	attacker.ConnectToPeer(node.Addr)
+	if !node.PeerDb.In(attacker.Addr) {
+		t.Fatalf("Nodes did not connect")
+	}
+	fp, _ := id.Fingerprint(&attacker.Id.PrivateKey.PublicKey)
+
+	a := address.New(node.Addr, 0)
+	for i := 0; i < 5; i++ {
+		if _, err := a.AddMemberRPC(context.Background(), attacker.Conns, &proto.EncKeysMem{Encryptedstuff: "garbage"}); err == nil {
+			t.Errorf("Undecryptable AddMember was accepted")
+		}
+	}
+	if _, err := a.GetAddressesRPC(context.Background(), attacker.Conns, &proto.GetAddressesRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected banned peer to be refused, got %v", err)
+	}
+	if node.PeerDb.In(attacker.Addr) {
+		t.Errorf("Banned peer was not disconnected")
+	}
+	bans := node.Bans()
+	if len(bans) != 1 || bans[0].ID != fp {
+		t.Errorf("Expected ban on %v, got %v", fp, bans)
+	}
+
+	node.Unban(fp)
+	attacker.ConnectToPeer(node.Addr)
+	if !node.PeerDb.In(attacker.Addr) {
+		t.Errorf("Peer could not reconnect after its ban was lifted")
+	}
+}
+
+func TestManualBan(t *testing.T) {
+	nodes := memNodes(t, 2)
+	node1, node2 := nodes[0], nodes[1]
+	defer node1.Kill()
+	defer node2.Kill()
+
+	fp, _ := id.Fingerprint(&node2.Id.PrivateKey.PublicKey)
+	node1.Ban(fp, 500*time.Millisecond)
+	node1.ConnectToPeer(node2.Addr)
+	node2.ConnectToPeer(node1.Addr)
+	if node1.PeerDb.In(node2.Addr) || node2.PeerDb.In(node1.Addr) {
+		t.Errorf("Banned node was peered")
+	}
+
+	time.Sleep(600 * time.Millisecond)
+	if len(node1.Bans()) != 0 {
+		t.Errorf("Ban did not expire")
+	}
+	node2.ConnectToPeer(node1.Addr)
+	if !node1.PeerDb.In(node2.Addr) {
+		t.Errorf("Node could not connect after its ban expired")
+	}
+}
+
+func TestHostBan(t *testing.T) {
+	node1 := pkg.New(pkg.DefaultConfig(test.GetFreePort()))
+	node2 := pkg.New(pkg.DefaultConfig(test.GetFreePort()))
+	test.Start(t, node1)
+	test.Start(t, node2)
+	defer node1.Kill()
+	defer node2.Kill()
+
+	// Under TLS callers identify by key, but their host is still
+	// checked.
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		node1.Ban(host, time.Hour)
+	}
+	a := address.New(node1.Addr, 0)
+	if _, err := a.GetAddressesRPC(context.Background(), node2.Conns, &proto.GetAddressesRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected caller from a banned host to be refused, got %v", err)
+	}
+}
+
+func TestScoresDecay(t *testing.T) {
+	db, _ := peer.NewDb(true, 8, "localhost:1", "")
+	db.SetBanPolicy(100, time.Hour, 10*time.Millisecond)
+	db.Misbehaving("slow", 60)
+	time.Sleep(200 * time.Millisecond)
+	if s := db.Score("slow"); s > 50 {
+		t.Errorf("Score did not decay: %v", s)
+	}
+	if db.Misbehaving("slow", 50) {
+		t.Errorf("Faults far apart got a caller banned")
+	}
+
+	db.Misbehaving("fast", 60)
+	if !db.Misbehaving("fast", 50) {
+		t.Errorf("Faults close together did not get a caller banned")
+	}
+
+	db.SetBanPolicy(100, time.Hour, 0)
+	db.Misbehaving("kept", 60)
+	time.Sleep(50 * time.Millisecond)
+	if s := db.Score("kept"); s != 60 {
+		t.Errorf("Score decayed with decay disabled: %v", s)
+	}
+}
+
+func TestGroupKeySignedBySender(t *testing.T) {
+	mem := transport.NewMemory()
+	nodes := make([]*pkg.Node, 2)
+	for i := range nodes {
+		c := pkg.DefaultConfig(0)
+		c.Insecure = true
+		c.Transport = mem
+		// Every caller shares a host here, so bans would hit the
+		// leader too.
+		c.BanThreshold = 0
+		nodes[i] = pkg.New(c)
+		test.Start(t, nodes[i])
+		defer nodes[i].Kill()
+	}
+	node, leader := nodes[0], nodes[1]
+	leader.ConnectToPeer(node.Addr)
+	if !node.PeerDb.In(leader.Addr) {
+		t.Fatalf("Nodes did not connect")
+	}
+	before := node.Group.Key()
+
+	// Without TLS anyone can call, and encrypt to the node's key.
+	cm := address.NewConnManager(10, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	kick := func(sk *rsa.PrivateKey, sender string) error {
+		key := group.New().GenerateNewKeys()
+		gc := pkg.GroupChange{Members: []group.Member{{ID: "someone", Addr: "localhost:1"}}, Key: key, Sender: sender}
+		if sk != nil {
+			gc.SigOverKey, _ = utils.Sign(sk, key)
+		}
+		enc, err := utils.PubEncrypt(&node.Id.PrivateKey.PublicKey, gc.Serialize())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = address.New(node.Addr, 0).KickMemberRPC(context.Background(), cm, &proto.EncKeysMem{Encryptedstuff: enc})
+		return err
+	}
+	attacker, _ := utils.GenerateAsymKey()
+	if kick(nil, leader.Addr) == nil || kick(attacker, "") == nil {
+		t.Errorf("Unsigned group key was accepted")
+	}
+	if kick(attacker, leader.Addr) == nil {
+		t.Errorf("Group key signed by another key than the sender's was accepted")
+	}
+	if node.Group.Key() != before {
+		t.Fatalf("Group key changed after a rejected change")
+	}
+	if err := kick(leader.Id.PrivateKey, leader.Addr); err != nil {
+		t.Errorf("Group key signed by the sending peer was refused: %v", err)
+	}
+}
+
+func TestKeyRotationBansHost(t *testing.T) {
+	c := pkg.DefaultConfig(test.GetFreePort())
+	c.HostBanThreshold = 60
+	node := pkg.New(c)
+	test.Start(t, node)
+	defer node.Kill()
+
+	// Every attacker has a fresh key, but they share a host.
+	a := address.New(node.Addr, 0)
+	var fps []string
+	for i := 0; i < 4; i++ {
+		attacker := pkg.New(pkg.DefaultConfig(test.GetFreePort()))
+		test.Start(t, attacker)
+		defer attacker.Kill()
+		fp, _ := id.Fingerprint(&attacker.Id.PrivateKey.PublicKey)
+		fps = append(fps, fp)
+		_, err := a.AddMemberRPC(context.Background(), attacker.Conns, &proto.EncKeysMem{Encryptedstuff: "garbage"})
+		if i < 3 && status.Code(err) == codes.PermissionDenied {
+			t.Fatalf("Caller %v was refused before its host was banned", i)
+		}
+		if i == 3 && status.Code(err) != codes.PermissionDenied {
+			t.Errorf("Expected new key on a banned host to be refused, got %v", err)
+		}
+	}
+	for _, fp := range fps {
+		if node.PeerDb.IsBanned(fp) {
+			t.Errorf("Key %v was banned below its threshold", fp)
+		}
+	}
+}
+
+func TestScoresBounded(t *testing.T) {
+	db, _ := peer.NewDb(true, 8, "localhost:1", "")
+	db.SetBanPolicy(100, time.Hour, 0)
+	db.Misbehaving("worst", 50)
+	for i := 0; i < 20000; i++ {
+		db.Misbehaving(fmt.Sprint("rotated", i), 1)
+	}
+	if s := db.Score("worst"); s != 50 {
+		t.Errorf("Highest score was dropped for a flood of low ones: %v", s)
+	}
+}
@@ -0,0 +1,291 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"finalbruh/pkg"
+	"finalbruh/pkg/dht"
+	"finalbruh/pkg/transport"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// network delivers DHT queries between in-process tables.
+type network struct {
+	nodes map[string]*dht.DHT
+}
+
+type client struct {
+	nw   *network
+	from dht.Contact
+}
+
+func (c *client) get(addr string) (*dht.DHT, error) {
+	d, ok := c.nw.nodes[addr]
+	if !ok {
+		return nil, errors.New("unreachable")
+	}
+	d.Update(c.from)
+	return d, nil
+}
+
+func (c *client) FindNode(ctx context.Context, addr string, target dht.NodeID) ([]dht.Contact, error) {
+	d, err := c.get(addr)
+	if err != nil {
+		return nil, err
+	}
+	return d.HandleFindNode(target), nil
+}
+
+func (c *client) FindValue(ctx context.Context, addr string, key dht.NodeID) (*dht.Record, []dht.Contact, error) {
+	d, err := c.get(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, cs := d.HandleFindValue(key)
+	return r, cs, nil
+}
+
+func (c *client) Store(ctx context.Context, addr string, r *dht.Record) error {
+	d, err := c.get(addr)
+	if err != nil {
+		return err
+	}
+	return d.HandleStore(r)
+}
+
+func newNetwork(t *testing.T, size int) (*network, []dht.Contact) {
+	nw := &network{nodes: make(map[string]*dht.DHT)}
+	contacts := make([]dht.Contact, size)
+	for i := range contacts {
+		var nid dht.NodeID
+		if _, err := rand.Read(nid[:]); err != nil {
+			t.Fatal(err)
+		}
+		contacts[i] = dht.Contact{ID: nid, Addr: "localhost:" + strconv.Itoa(1000+i)}
+		nw.nodes[contacts[i].Addr] = dht.New(nid, &client{nw: nw, from: contacts[i]}, dht.DefaultK, dht.DefaultAlpha)
+	}
+	// Every node joins through the first one, then looks itself up
+	// again to learn of the nodes that joined after it.
+	for _, c := range contacts[1:] {
+		d := nw.nodes[c.Addr]
+		d.Update(contacts[0])
+		d.Lookup(context.Background(), c.ID)
+	}
+	for _, c := range contacts {
+		nw.nodes[c.Addr].Lookup(context.Background(), c.ID)
+	}
+	return nw, contacts
+}
+
+func TestLookupFindsClosest(t *testing.T) {
+	nw, contacts := newNetwork(t, 40)
+	var target dht.NodeID
+	if _, err := rand.Read(target[:]); err != nil {
+		t.Fatal(err)
+	}
+	// A lookup never returns the node running it.
+	from := contacts[len(contacts)-1]
+	sorted := append([]dht.Contact(nil), contacts[:len(contacts)-1]...)
+	sort.Slice(sorted, func(i, j int) bool {
+		di, dj := xor(sorted[i].ID, target), xor(sorted[j].ID, target)
+		return string(di[:]) < string(dj[:])
+	})
+	found := nw.nodes[from.Addr].Lookup(context.Background(), target)
+	if len(found) == 0 || found[0].ID != sorted[0].ID {
+		t.Fatalf("Lookup did not find the closest node to the target")
+	}
+}
+
+func xor(a, b dht.NodeID) dht.NodeID {
+	var d dht.NodeID
+	for i := range d {
+		d[i] = a[i] ^ b[i]
+	}
+	return d
+}
+
+func TestPublishAndResolve(t *testing.T) {
+	nw, contacts := newNetwork(t, 30)
+	sk, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := dht.NewRecord(sk, []string{"localhost:4242"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := nw.nodes[contacts[3].Addr].Publish(context.Background(), r); err != nil || n == 0 {
+		t.Fatalf("Publish stored the record on %v nodes: %v", n, err)
+	}
+	key, _ := r.ID()
+	got, err := nw.nodes[contacts[20].Addr].Resolve(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Could not resolve published record: %v", err)
+	}
+	if got.Addrs[0] != "localhost:4242" {
+		t.Fatalf("Resolved wrong address %v", got.Addrs[0])
+	}
+}
+
+func TestRecordVerification(t *testing.T) {
+	d := dht.New(dht.NodeID{}, &client{nw: &network{}}, 0, 0)
+	sk, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _ := dht.NewRecord(sk, []string{"localhost:1"})
+	time.Sleep(time.Millisecond)
+	newer, _ := dht.NewRecord(sk, []string{"localhost:2"})
+
+	forged := *newer
+	forged.Addrs = []string{"localhost:666"}
+	if d.HandleStore(&forged) == nil {
+		t.Fatalf("Stored a record whose addresses were tampered with")
+	}
+	other, _ := utils.GenerateAsymKey()
+	stolen := *newer
+	stolen.PublicKey = &other.PublicKey
+	if d.HandleStore(&stolen) == nil {
+		t.Fatalf("Stored a record signed by another key")
+	}
+
+	if err := d.HandleStore(newer); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.HandleStore(old); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := newer.ID()
+	if got := d.Local(key); got == nil || got.Addrs[0] != "localhost:2" {
+		t.Fatalf("An older record replaced a newer one")
+	}
+}
+
+func TestStoreBounded(t *testing.T) {
+	d := dht.New(dht.NodeID{}, &client{nw: &network{}}, 0, 0)
+	d.SetRecordLimit(8)
+	var keys []dht.NodeID
+	for i := 0; i < 30; i++ {
+		sk, err := utils.GenerateAsymKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, _ := dht.NewRecord(sk, []string{"localhost:1"})
+		key, _ := r.ID()
+		keys = append(keys, key)
+		if err := d.HandleStore(r); err != nil && err != dht.ErrNotStored {
+			t.Fatal(err)
+		}
+	}
+	if n := d.Records(); n != 8 {
+		t.Fatalf("Store holds %v records, want 8", n)
+	}
+	// The records kept are those closest to the node's own ID, which
+	// is 0, so a key is its own distance.
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+	for i, key := range keys {
+		if (d.Local(key) != nil) != (i < 8) {
+			t.Fatalf("Record %v of 30 by distance kept: %v", i, d.Local(key) != nil)
+		}
+	}
+
+	// Records are refused for keys that K known nodes are closer to.
+	sk, _ := utils.GenerateAsymKey()
+	r, _ := dht.NewRecord(sk, []string{"localhost:1"})
+	key, _ := r.ID()
+	for i := 0; i < dht.DefaultK; i++ {
+		near := key
+		near[dht.IDBytes-1] ^= byte(i + 1)
+		d.Update(dht.Contact{ID: near, Addr: "localhost:" + strconv.Itoa(2000+i)})
+	}
+	if err := d.HandleStore(r); err != dht.ErrNotStored {
+		t.Fatalf("Stored a record other nodes are closer to: %v", err)
+	}
+}
+
+func newNode(mem *transport.Memory, seeds []string) *pkg.Node {
+	c := pkg.DefaultConfig(0)
+	c.Transport = mem
+	c.Seeds = seeds
+	c.ProbeInterval = time.Hour
+	return pkg.New(c)
+}
+
+func TestResolveIdentity(t *testing.T) {
+	mem := transport.NewMemory()
+	a := newNode(mem, nil)
+	test.Start(t, a)
+	defer a.Kill()
+	b := newNode(mem, []string{a.Addr})
+	test.Start(t, b)
+	defer b.Kill()
+	c := newNode(mem, []string{b.Addr})
+	test.Start(t, c)
+	defer c.Kill()
+
+	// c's record reaches a through the lookup c runs when publishing.
+	var addr string
+	ok := test.WaitFor(func() bool {
+		var err error
+		addr, err = a.Resolve(c.Fingerprint())
+		return err == nil
+	}, 5*time.Second)
+	if !ok || addr != c.Addr {
+		t.Fatalf("a resolved c's identity to %q, want %q", addr, c.Addr)
+	}
+	if !a.PeerDb.In(c.Addr) {
+		t.Fatalf("Resolve did not connect to the resolved node")
+	}
+}
+
+func TestMovedMemberStaysInGroup(t *testing.T) {
+	mem := transport.NewMemory()
+	a := newNode(mem, nil)
+	test.Start(t, a)
+	defer a.Kill()
+	b := newNode(mem, []string{a.Addr})
+	test.Start(t, b)
+	defer b.Kill()
+
+	c1 := newNode(mem, []string{b.Addr})
+	test.Start(t, c1)
+	a.ConnectToPeer(c1.Addr)
+	a.NewGroup()
+	a.AddAMember(c1.Addr)
+	c1.Kill()
+
+	// c comes back with the same key at a new address.
+	c2 := pkg.NewWithID(c1.Conf, c1.Id)
+	test.Start(t, c2)
+	defer c2.Kill()
+	if c2.Addr == c1.Addr {
+		t.Fatalf("Restarted node kept its address")
+	}
+	if !test.WaitFor(func() bool {
+		r := b.DHT.Local(c2.DHT.Self())
+		return r != nil && r.Addrs[0] == c2.Addr
+	}, 5*time.Second) {
+		t.Fatalf("c did not publish its new address")
+	}
+
+	a.MessageMyGroup("hello")
+	ok := test.WaitFor(func() bool {
+		m := a.Group.Get(c2.Fingerprint())
+		return m != nil && m.Addr.Addr == c2.Addr
+	}, 5*time.Second)
+	if !ok {
+		t.Fatalf("Group did not follow the member to its new address")
+	}
+	if len(a.Group.Members()) != 1 {
+		t.Fatalf("Group has %v members, want 1", len(a.Group.Members()))
+	}
+}
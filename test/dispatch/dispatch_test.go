@@ -0,0 +1,278 @@
+package dispatch
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/dispatch"
+	"finalbruh/pkg/faults"
+	"finalbruh/pkg/peer"
+	"finalbruh/pkg/transport"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"fmt"
+	"golang.org/x/net/context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerLimitAndOrder(t *testing.T) {
+	d := dispatch.New(2, 100)
+	var running, peak int32
+	var mu sync.Mutex
+	order := make(map[string][]int)
+	for i := 0; i < 20; i++ {
+		for _, key := range []string{"a", "b", "c", "d"} {
+			key, i := key, i
+			if err := d.Submit(context.Background(), key, func() {
+				if r := atomic.AddInt32(&running, 1); r > atomic.LoadInt32(&peak) {
+					atomic.StoreInt32(&peak, r)
+				}
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				order[key] = append(order[key], i)
+				mu.Unlock()
+				atomic.AddInt32(&running, -1)
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	d.Close()
+	d.Wait()
+	if peak > 2 {
+		t.Fatalf("%v jobs ran at once with 2 workers", peak)
+	}
+	for key, got := range order {
+		for i, v := range got {
+			if v != i {
+				t.Fatalf("Jobs for %v ran out of order: %v", key, got)
+			}
+		}
+	}
+	if s := d.Stats(); s.Done != 80 || s.Queued != 0 || s.Workers != 0 {
+		t.Fatalf("Unexpected stats after draining: %+v", s)
+	}
+	if d.Submit(context.Background(), "a", func() {}) != dispatch.ErrClosed {
+		t.Fatalf("Closed dispatcher accepted a job")
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	d := dispatch.New(1, 2)
+	release := make(chan struct{})
+	block := func() { <-release }
+	// One job runs and two wait, filling the queue.
+	for i := 0; i < 3; i++ {
+		if err := d.Submit(context.Background(), "slow", block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !test.WaitFor(func() bool { return d.Stats().Queues["slow"] == 2 }, time.Second) {
+		t.Fatalf("Queue depth not reported: %+v", d.Stats())
+	}
+	if d.TrySubmit("slow", block) {
+		t.Fatalf("Full queue accepted a job")
+	}
+	if d.Stats().Dropped != 1 {
+		t.Fatalf("Refused job not counted")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Submit(ctx, "slow", block); err != context.DeadlineExceeded {
+		t.Fatalf("Submit to a full queue returned %v, want to wait", err)
+	}
+	// Room frees up as jobs run.
+	done := make(chan error, 1)
+	go func() { done <- d.Submit(context.Background(), "slow", func() {}) }()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	d.Wait()
+}
+
+func TestFairness(t *testing.T) {
+	d := dispatch.New(2, 100)
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < 10; i++ {
+		_ = d.Submit(context.Background(), "slow", func() { <-release })
+	}
+	// The slow key holds at most one worker, so the others get through.
+	var fast int32
+	for i := 0; i < 10; i++ {
+		_ = d.Submit(context.Background(), "fast", func() { atomic.AddInt32(&fast, 1) })
+	}
+	if !test.WaitFor(func() bool { return atomic.LoadInt32(&fast) == 10 }, time.Second) {
+		t.Fatalf("A slow peer held back another: %+v", d.Stats())
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	d := dispatch.New(1, 10)
+	release := make(chan struct{})
+	var ran int32
+	_ = d.Submit(context.Background(), "dead", func() { <-release })
+	if !test.WaitFor(func() bool { return d.Stats().Busy == 1 }, time.Second) {
+		t.Fatalf("First job did not start: %+v", d.Stats())
+	}
+	for i := 0; i < 5; i++ {
+		_ = d.Submit(context.Background(), "dead", func() { atomic.AddInt32(&ran, 1) })
+	}
+	_ = d.Submit(context.Background(), "live", func() { atomic.AddInt32(&ran, 10) })
+	if k := d.Discard("dead"); k != 5 {
+		t.Fatalf("Discarded %v jobs, want 5", k)
+	}
+	close(release)
+	d.Close()
+	d.Wait()
+	if ran != 10 {
+		t.Fatalf("Discarded jobs ran or others did not: %v", ran)
+	}
+	if s := d.Stats(); s.Dropped != 5 || s.Queued != 0 {
+		t.Fatalf("Unexpected stats after discarding: %+v", s)
+	}
+}
+
+// TestBoundedFanOut messages a group in a burst over slow links and
+// checks the node never runs more senders than configured.
+func TestBoundedFanOut(t *testing.T) {
+	mem := transport.NewMemory()
+	nw := faults.NewNetwork(1)
+	nodes := make([]*pkg.Node, 4)
+	for i := range nodes {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.Faults = nw
+		c.SendWorkers = 2
+		c.SendQueueLimit = 4
+		// Every message is valid, but the test sends many.
+		c.PeerRate = 0
+		c.GlobalRate = 0
+		nodes[i] = pkg.New(c)
+		test.Start(t, nodes[i])
+		defer nodes[i].Kill()
+	}
+	leader := nodes[0]
+	leader.NewGroup()
+	for _, n := range nodes[1:] {
+		leader.ConnectToPeer(n.Addr)
+		leader.AddAMember(n.Addr)
+	}
+	idle := func() bool {
+		s := leader.OutboundStats()
+		return s.Queued == 0 && s.Workers == 0
+	}
+	if !test.WaitFor(idle, 5*time.Second) {
+		t.Fatalf("Group changes were not sent")
+	}
+	before := leader.OutboundStats().Done
+	for _, n := range nodes[1:] {
+		nw.SetLink(leader.Addr, n.Addr, faults.Fault{Delay: 20 * time.Millisecond})
+	}
+
+	stop := make(chan struct{})
+	violations := make(chan dispatch.Stats, 1)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			s := leader.OutboundStats()
+			over := s.Workers > 2
+			for _, q := range s.Queues {
+				over = over || q > 4
+			}
+			if over {
+				select {
+				case violations <- s:
+				default:
+				}
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		leader.MessageMyGroup("burst")
+	}
+	ok := test.WaitFor(func() bool {
+		return leader.OutboundStats().Done-before >= 60 && idle()
+	}, 10*time.Second)
+	close(stop)
+	if !ok {
+		t.Fatalf("Burst was not delivered: %+v", leader.OutboundStats())
+	}
+	select {
+	case s := <-violations:
+		t.Fatalf("Dispatcher exceeded its bounds: %+v", s)
+	default:
+	}
+}
+
+// TestBoundedMemberDials adds a node to a group with many members it
+// does not know and checks it dials them from its bounded pool.
+func TestBoundedMemberDials(t *testing.T) {
+	mem := transport.NewMemory()
+	nw := faults.NewNetwork(1)
+	newNode := func() *pkg.Node {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.Faults = nw
+		c.SendWorkers = 2
+		return pkg.New(c)
+	}
+	leader, member := newNode(), newNode()
+	test.Start(t, leader)
+	test.Start(t, member)
+	defer leader.Kill()
+	defer member.Kill()
+	leader.NewGroup()
+	const others = 10
+	for i := 0; i < others; i++ {
+		sk, err := utils.GenerateAsymKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := fmt.Sprintf("10.0.%d.1:8000", i)
+		leader.Group.AddMember(peer.New(address.New(addr, 0), 0, &sk.PublicKey))
+		leader.Group.SetOffline(addr, true)
+		// Dialing them is slow, so the dials overlap.
+		nw.SetLink(member.Addr, addr, faults.Fault{Delay: 50 * time.Millisecond})
+	}
+
+	stop := make(chan struct{})
+	peak := make(chan int, 1)
+	go func() {
+		max := 0
+		defer func() { peak <- max }()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if w := member.OutboundStats().Workers; w > max {
+				max = w
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	leader.ConnectToPeer(member.Addr)
+	leader.AddAMember(member.Addr)
+	ok := test.WaitFor(func() bool {
+		s := member.OutboundStats()
+		return s.Done >= others && s.Workers == 0
+	}, 10*time.Second)
+	close(stop)
+	if !ok {
+		t.Fatalf("New members were not dialed: %+v", member.OutboundStats())
+	}
+	if max := <-peak; max > 2 {
+		t.Fatalf("Member dials ran on %v workers, want at most 2", max)
+	}
+}
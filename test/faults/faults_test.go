@@ -0,0 +1,131 @@
+package faults
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/faults"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/transport"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// call runs one RPC from "a:1" to "b:1" through the client interceptor
+// and returns how many times it was delivered.
+func call(t *testing.T, nw *faults.Network) (int32, error) {
+	cc, err := grpc.Dial("b:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer cc.Close()
+	var delivered int32
+	invoker := func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		atomic.AddInt32(&delivered, 1)
+		return nil
+	}
+	intercept := nw.ClientInterceptor(func() string { return "a:1" })
+	err = intercept(context.Background(), "/BrunoCoin/GroupMessage",
+		&proto.GroupIM{}, &proto.Empty{}, cc, invoker)
+	return atomic.LoadInt32(&delivered), err
+}
+
+func TestFaults(t *testing.T) {
+	nw := faults.NewNetwork(1)
+
+	if n, err := call(t, nw); n != 1 || err != nil {
+		t.Errorf("Expected clean delivery, got %v deliveries, %v", n, err)
+	}
+
+	nw.Set(faults.Fault{Drop: 1})
+	if n, err := call(t, nw); n != 0 || status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected drop, got %v deliveries, %v", n, err)
+	}
+
+	nw.Set(faults.Fault{Duplicate: 1})
+	if n, err := call(t, nw); n != 2 || err != nil {
+		t.Errorf("Expected duplicate, got %v deliveries, %v", n, err)
+	}
+
+	nw.Set(faults.Fault{Delay: 50 * time.Millisecond})
+	start := time.Now()
+	if _, err := call(t, nw); err != nil || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("Expected delayed delivery, took %v, %v", time.Since(start), err)
+	}
+
+	nw.Set(faults.Fault{Reorder: 1, ReorderWindow: time.Millisecond})
+	if n, err := call(t, nw); n != 0 || err != nil {
+		t.Errorf("Expected reordered RPC to be acknowledged before delivery, got %v, %v", n, err)
+	}
+
+	nw.Heal()
+	nw.SetLink("a:1", "b:1", faults.Fault{Drop: 1})
+	if n, _ := call(t, nw); n != 0 {
+		t.Errorf("Expected link fault to apply")
+	}
+
+	nw.Heal()
+	nw.Name("left", "a:1")
+	nw.Name("right", "b:1")
+	nw.Partition("left", "right")
+	if n, err := call(t, nw); n != 0 || status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected partition, got %v deliveries, %v", n, err)
+	}
+	nw.Heal()
+	if n, err := call(t, nw); n != 1 || err != nil {
+		t.Errorf("Expected healed delivery, got %v deliveries, %v", n, err)
+	}
+}
+
+func TestGroupConvergesAfterPartition(t *testing.T) {
+	mem := transport.NewMemory()
+	nw := faults.NewNetwork(1)
+	nodes := make([]*pkg.Node, 3)
+	for i := range nodes {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.Faults = nw
+		nodes[i] = pkg.New(c)
+		test.Start(t, nodes[i])
+		defer nodes[i].Kill()
+	}
+	leader := nodes[0]
+	leader.NewGroup()
+	for _, n := range nodes[1:] {
+		leader.ConnectToPeer(n.Addr)
+		leader.AddAMember(n.Addr)
+	}
+	converged := func() bool {
+		for _, n := range nodes[1:] {
+			if n.Group.Key() != leader.Group.Key() || len(n.Group.Members()) != len(leader.Group.Members()) {
+				return false
+			}
+		}
+		return true
+	}
+	if !test.WaitFor(converged, 5*time.Second) {
+		t.Fatalf("Group did not form")
+	}
+
+	// The key rotation during the partition never reaches nodes[2].
+	nw.Name("majority", nodes[0].Addr, nodes[1].Addr)
+	nw.Name("minority", nodes[2].Addr)
+	nw.Partition("majority", "minority")
+	leader.AddAMember(nodes[1].Addr)
+	time.Sleep(200 * time.Millisecond)
+	if nodes[2].Group.Key() == leader.Group.Key() {
+		t.Fatalf("Partitioned node received the new key")
+	}
+
+	nw.Heal()
+	leader.AddAMember(nodes[1].Addr)
+	if !test.WaitFor(converged, 5*time.Second) {
+		t.Errorf("Group did not converge after the partition healed")
+	}
+}
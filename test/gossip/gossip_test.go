@@ -0,0 +1,273 @@
+package gossip
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/transport"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"fmt"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func newNode(mem *transport.Memory, legacy bool) *pkg.Node {
+	c := pkg.DefaultConfig(0)
+	c.Insecure = true
+	c.Transport = mem
+	c.AcceptUnsignedAddrs = legacy
+	return pkg.New(c)
+}
+
+func send(t *testing.T, mem *transport.Memory, to string, addrs ...*proto.Address) {
+	cm := address.NewConnManager(10, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	if _, err := address.New(to, 0).SendAddressesRPC(context.Background(), cm, &proto.Addresses{Addrs: addrs}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnsignedAddressesRefused(t *testing.T) {
+	mem := transport.NewMemory()
+	node := newNode(mem, false)
+	test.Start(t, node)
+	defer node.Kill()
+	legacy := newNode(mem, true)
+	test.Start(t, legacy)
+	defer legacy.Kill()
+
+	unsigned := &proto.Address{Addr: "localhost:7001", LastSeen: 1}
+	send(t, mem, node.Addr, unsigned)
+	send(t, mem, legacy.Addr, unsigned)
+	if node.AddrDb.Get("localhost:7001") != nil {
+		t.Errorf("Stored an unsigned address")
+	}
+	if legacy.AddrDb.Get("localhost:7001") == nil {
+		t.Errorf("Unsigned address refused in compatibility mode")
+	}
+}
+
+func TestLastSeenEncoding(t *testing.T) {
+	now := time.Now()
+	a := address.Deserialize(address.New("localhost:7001", now.UnixNano()).Serialize())
+	if a.LastSeen != now.UnixNano() {
+		t.Errorf("LastSeen did not survive encoding: %v != %v", a.LastSeen, now.UnixNano())
+	}
+	// Older nodes only send Unix seconds.
+	legacy := address.Deserialize(&proto.Address{Addr: "localhost:7001", LastSeen: uint32(now.Unix())})
+	if legacy.LastSeen != time.Unix(now.Unix(), 0).UnixNano() {
+		t.Errorf("Legacy LastSeen decoded as %v", legacy.LastSeen)
+	}
+	if enc := address.New("localhost:7001", now.UnixNano()).Serialize(); enc.LastSeen != uint32(now.Unix()) {
+		t.Errorf("Legacy LastSeen encoded as %v", enc.LastSeen)
+	}
+	future := &proto.Address{Addr: "localhost:7001", LastSeenNs: now.Add(time.Hour).UnixNano()}
+	if address.Deserialize(future).LastSeen != 0 {
+		t.Errorf("Kept a LastSeen in the future")
+	}
+}
+
+func TestUnsignedLastSeenOrder(t *testing.T) {
+	mem := transport.NewMemory()
+	node := newNode(mem, true)
+	test.Start(t, node)
+	defer node.Kill()
+
+	// LastSeen values seconds apart used to wrap around.
+	now := time.Now()
+	at := func(d time.Duration) *proto.Address {
+		return address.New("localhost:7001", now.Add(d).UnixNano()).Serialize()
+	}
+	send(t, mem, node.Addr, at(-10*time.Second))
+	send(t, mem, node.Addr, at(-5*time.Second))
+	send(t, mem, node.Addr, at(-20*time.Second))
+	if got := node.AddrDb.Get("localhost:7001"); got == nil || got.LastSeen != now.Add(-5*time.Second).UnixNano() {
+		t.Errorf("Stored LastSeen is not the latest one sent")
+	}
+}
+
+func TestSignedAddresses(t *testing.T) {
+	mem := transport.NewMemory()
+	node := newNode(mem, false)
+	test.Start(t, node)
+	defer node.Kill()
+
+	sk, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _ := address.Announce(sk, "localhost:7002")
+	time.Sleep(time.Millisecond)
+	ann, _ := address.Announce(sk, "localhost:7002")
+
+	forged := ann.Serialize()
+	forged.Addr = "localhost:7003"
+	send(t, mem, node.Addr, forged)
+	if node.AddrDb.Get("localhost:7003") != nil {
+		t.Errorf("Stored an address the signature does not cover")
+	}
+
+	send(t, mem, node.Addr, ann.Serialize())
+	stored := node.AddrDb.Get("localhost:7002")
+	if stored == nil || !stored.Signed() {
+		t.Fatalf("Valid signed address was not stored")
+	}
+
+	// Replaying an older announcement, or the same one with a fresher
+	// LastSeen, does not make the address look any fresher.
+	replay := old.Serialize()
+	replay.LastSeenNs = stored.LastSeen + 1000
+	send(t, mem, node.Addr, replay)
+	if got := node.AddrDb.Get("localhost:7002"); got.Timestamp != ann.Timestamp || got.LastSeen != stored.LastSeen {
+		t.Errorf("Older announcement replaced a newer one")
+	}
+}
+
+func TestAddressKeySticks(t *testing.T) {
+	mem := transport.NewMemory()
+	node := newNode(mem, false)
+	test.Start(t, node)
+	defer node.Kill()
+
+	owner, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ann, _ := address.Announce(owner, "localhost:7002")
+	send(t, mem, node.Addr, ann.Serialize())
+
+	// A newer record signed by another key does not take the address.
+	time.Sleep(time.Millisecond)
+	hijack, _ := address.Announce(other, "localhost:7002")
+	send(t, mem, node.Addr, hijack.Serialize())
+	if got := node.AddrDb.Get("localhost:7002"); got == nil || !got.SignedBy(&owner.PublicKey) {
+		t.Fatalf("Record signed by a second key replaced the owner's")
+	}
+
+	time.Sleep(time.Millisecond)
+	fresh, _ := address.Announce(owner, "localhost:7002")
+	send(t, mem, node.Addr, fresh.Serialize())
+	if got := node.AddrDb.Get("localhost:7002"); got == nil || got.Timestamp != fresh.Timestamp {
+		t.Errorf("Owner could not refresh its address")
+	}
+}
+
+func TestBroadcastIsSigned(t *testing.T) {
+	mem := transport.NewMemory()
+	a := newNode(mem, false)
+	test.Start(t, a)
+	defer a.Kill()
+	b := newNode(mem, false)
+	test.Start(t, b)
+	defer b.Kill()
+	c := newNode(mem, false)
+	test.Start(t, c)
+	defer c.Kill()
+
+	b.ConnectToPeer(a.Addr)
+	b.ConnectToPeer(c.Addr)
+	c.BroadcastAddr()
+
+	// b relays c's announcement to a, which has never talked to c.
+	ok := test.WaitFor(func() bool {
+		got := a.AddrDb.Get(c.Addr)
+		return got != nil && got.SignedBy(&c.Id.PrivateKey.PublicKey)
+	}, 5*time.Second)
+	if !ok {
+		t.Fatalf("Signed announcement did not reach a")
+	}
+}
+
+func TestGetAddressesSampled(t *testing.T) {
+	mem := transport.NewMemory()
+	c := pkg.DefaultConfig(0)
+	c.Insecure = true
+	c.Transport = mem
+	c.AddrSampleSize = 10
+	node := pkg.New(c)
+	test.Start(t, node)
+	defer node.Kill()
+	old := time.Now().Add(-time.Hour).UnixNano()
+	for i := 0; i < 40; i++ {
+		seen := time.Now().UnixNano()
+		if i%2 == 0 {
+			seen = old
+		}
+		_ = node.AddrDb.Add(address.New(fmt.Sprintf("%d.1.1.1:8000", 20+i), seen))
+	}
+
+	cm := address.NewConnManager(10, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	to := address.New(node.Addr, 0)
+	get := func(req *proto.GetAddressesRequest) []*proto.Address {
+		reply, err := to.GetAddressesRPC(context.Background(), cm, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reply.Addrs
+	}
+	if n := len(get(&proto.GetAddressesRequest{})); n != 10 {
+		t.Errorf("Returned %v addresses, want the sample size 10", n)
+	}
+	if n := len(get(&proto.GetAddressesRequest{Limit: 1000})); n != 10 {
+		t.Errorf("Caller raised the sample size to %v", n)
+	}
+	if n := len(get(&proto.GetAddressesRequest{Limit: 3})); n != 3 {
+		t.Errorf("Returned %v addresses, want 3", n)
+	}
+	for _, a := range get(&proto.GetAddressesRequest{SeenAfter: old}) {
+		if a.LastSeenNs <= old {
+			t.Fatalf("Returned an address last seen before the cutoff")
+		}
+	}
+}
+
+func TestListAddresses(t *testing.T) {
+	mem := transport.NewMemory()
+	newTLSNode := func(listers ...string) *pkg.Node {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.AddrPageSize = 7
+		c.AddrListers = listers
+		n := pkg.New(c)
+		test.Start(t, n)
+		return n
+	}
+	tool := newTLSNode()
+	defer tool.Kill()
+	stranger := newTLSNode()
+	defer stranger.Kill()
+	node := newTLSNode(tool.Fingerprint())
+	defer node.Kill()
+	for i := 0; i < 30; i++ {
+		_ = node.AddrDb.Add(address.New(fmt.Sprintf("%d.1.1.1:8000", 20+i), 1))
+	}
+
+	all, err := address.New(node.Addr, 0).ListAllAddresses(context.Background(), tool.Conns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(node.AddrDb.List()) {
+		t.Fatalf("Listed %v addresses, want %v", len(all), len(node.AddrDb.List()))
+	}
+	seen := make(map[string]bool)
+	for _, a := range all {
+		if seen[a.Addr] {
+			t.Fatalf("Listed %v twice", a.Addr)
+		}
+		seen[a.Addr] = true
+	}
+	_, err = address.New(node.Addr, 0).ListAddressesRPC(context.Background(), stranger.Conns, &proto.ListAddressesRequest{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Unauthorized caller listed addresses: %v", err)
+	}
+}
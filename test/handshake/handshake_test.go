@@ -0,0 +1,263 @@
+package handshake
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/faults"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/transport"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"fmt"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"testing"
+	"time"
+)
+
+func insecureNode() *pkg.Node {
+	c := pkg.DefaultConfig(test.GetFreePort())
+	c.Insecure = true
+	return pkg.New(c)
+}
+
+func TestHandshake(t *testing.T) {
+	node1 := insecureNode()
+	node2 := insecureNode()
+	test.Start(t, node1)
+	test.Start(t, node2)
+	defer node1.Kill()
+	defer node2.Kill()
+
+	node1.ConnectToPeer(node2.Addr)
+
+	test.ChkNdPrs(t, node1, []*pkg.Node{node2})
+	test.ChkNdPrs(t, node2, []*pkg.Node{node1})
+	if !node1.PeerDb.Get(node2.Addr).PublicKey.Equal(&node2.Id.PrivateKey.PublicKey) {
+		t.Errorf("Node recorded the wrong key for its peer")
+	}
+}
+
+func TestForgedKeyRejected(t *testing.T) {
+	node := insecureNode()
+	test.Start(t, node)
+	defer node.Kill()
+
+	victim, _ := utils.GenerateAsymKey()
+	attacker, _ := utils.GenerateAsymKey()
+	victimPk, _ := utils.EncodePublicKey(&victim.PublicKey)
+	nonce, _ := utils.RandomBytes(32)
+
+	cm := address.NewConnManager(10, 0, nil)
+	defer cm.Close()
+	a := address.New(node.Addr, 0)
+	fake := "fake:1234"
+
+	reply, err := a.VersionRPC(context.Background(), cm, &proto.VersionRequest{
+		AddrYou: node.Addr,
+		AddrMe:  fake,
+		SerPk:   victimPk,
+		Nonce:   nonce,
+	})
+	if err != nil {
+		t.Fatalf("VersionRPC failed: %v", err)
+	}
+	msg := fmt.Sprintf("version:%x:%v:%v", reply.Nonce, fake, node.Addr)
+	sig, _ := utils.Sign(attacker, msg)
+	if _, err := a.VerAckRPC(context.Background(), cm, &proto.VersionAck{AddrMe: fake, Sig: sig}); err == nil {
+		t.Errorf("Expected VerAck signed with the wrong key to fail")
+	}
+	if node.PeerDb.In(fake) {
+		t.Errorf("Node peered with an address that did not prove its key")
+	}
+}
+
+func TestSignatureBoundToAddresses(t *testing.T) {
+	node := insecureNode()
+	test.Start(t, node)
+	defer node.Kill()
+
+	sk, _ := utils.GenerateAsymKey()
+	pk, _ := utils.EncodePublicKey(&sk.PublicKey)
+	nonce, _ := utils.RandomBytes(32)
+
+	cm := address.NewConnManager(10, 0, nil)
+	defer cm.Close()
+	a := address.New(node.Addr, 0)
+	me := "me:1234"
+
+	reply, err := a.VersionRPC(context.Background(), cm, &proto.VersionRequest{
+		AddrYou: node.Addr,
+		AddrMe:  me,
+		SerPk:   pk,
+		Nonce:   nonce,
+	})
+	if err != nil {
+		t.Fatalf("VersionRPC failed: %v", err)
+	}
+	msg := fmt.Sprintf("version:%x:%v:%v", reply.Nonce, me, "someone-else:1")
+	sig, _ := utils.Sign(sk, msg)
+	if _, err := a.VerAckRPC(context.Background(), cm, &proto.VersionAck{AddrMe: me, Sig: sig}); err == nil {
+		t.Errorf("Expected VerAck bound to other addresses to fail")
+	}
+	if node.PeerDb.In(me) {
+		t.Errorf("Node peered after a signature over the wrong addresses")
+	}
+}
+
+func TestSpoofedAddrMeRejected(t *testing.T) {
+	node := insecureNode()
+	victim := insecureNode()
+	test.Start(t, node)
+	test.Start(t, victim)
+	defer node.Kill()
+	defer victim.Kill()
+
+	// The attacker proves its own key but claims the victim's address.
+	sk, _ := utils.GenerateAsymKey()
+	pk, _ := utils.EncodePublicKey(&sk.PublicKey)
+	nonce, _ := utils.RandomBytes(32)
+
+	cm := address.NewConnManager(10, 0, nil)
+	defer cm.Close()
+	a := address.New(node.Addr, 0)
+
+	reply, err := a.VersionRPC(context.Background(), cm, &proto.VersionRequest{
+		AddrYou: node.Addr,
+		AddrMe:  victim.Addr,
+		SerPk:   pk,
+		Nonce:   nonce,
+	})
+	if err != nil {
+		t.Fatalf("VersionRPC failed: %v", err)
+	}
+	msg := fmt.Sprintf("version:%x:%v:%v", reply.Nonce, victim.Addr, node.Addr)
+	sig, _ := utils.Sign(sk, msg)
+	if _, err := a.VerAckRPC(context.Background(), cm, &proto.VersionAck{AddrMe: victim.Addr, Sig: sig}); err == nil {
+		t.Errorf("Expected VerAck from a spoofed address to fail")
+	}
+	if node.PeerDb.In(victim.Addr) {
+		t.Errorf("Node peered with a node that does not hold the address it claimed")
+	}
+
+	// The victim can still connect from its own address.
+	victim.ConnectToPeer(node.Addr)
+	if p := node.PeerDb.Get(victim.Addr); p == nil || !p.PublicKey.Equal(&victim.Id.PrivateKey.PublicKey) {
+		t.Errorf("Victim could not connect after its address was spoofed")
+	}
+}
+
+func TestPendingHandshakeKept(t *testing.T) {
+	mem := transport.NewMemory()
+	nw := faults.NewNetwork(1)
+	newNode := func() *pkg.Node {
+		c := pkg.DefaultConfig(0)
+		c.Insecure = true
+		c.Transport = mem
+		c.Faults = nw
+		return pkg.New(c)
+	}
+	node, victim := newNode(), newNode()
+	test.Start(t, node)
+	test.Start(t, victim)
+	defer node.Kill()
+	defer victim.Kill()
+
+	// The victim's VerAck arrives late, leaving time for another
+	// caller to send a Version claiming its address.
+	nw.SetLink(victim.Addr, node.Addr, faults.Fault{Delay: 300 * time.Millisecond})
+	res := victim.Connect(node.Addr)
+	awaiting := func() bool { return node.HandshakeState(victim.Addr) == pkg.HandshakeAwaitingAck }
+	if !test.WaitFor(awaiting, 5*time.Second) {
+		t.Fatalf("Victim's Version did not arrive")
+	}
+
+	sk, _ := utils.GenerateAsymKey()
+	pk, _ := utils.EncodePublicKey(&sk.PublicKey)
+	nonce, _ := utils.RandomBytes(32)
+	cm := address.NewConnManager(10, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	_, err := address.New(node.Addr, 0).VersionRPC(context.Background(), cm, &proto.VersionRequest{
+		AddrYou: node.Addr,
+		AddrMe:  victim.Addr,
+		SerPk:   pk,
+		Nonce:   nonce,
+	})
+	if err == nil {
+		t.Errorf("Version under another key replaced a pending one")
+	}
+	if err := <-res; err != nil {
+		t.Fatalf("Victim's handshake failed: %v", err)
+	}
+	if !node.PeerDb.In(victim.Addr) {
+		t.Errorf("Node did not peer with the victim")
+	}
+}
+
+func TestHandshakeStates(t *testing.T) {
+	node1 := insecureNode()
+	node2 := insecureNode()
+	test.Start(t, node1)
+	test.Start(t, node2)
+	defer node1.Kill()
+	defer node2.Kill()
+	events := node2.SubscribePeers()
+
+	// Concurrent dials share one handshake.
+	results := []<-chan error{node1.Connect(node2.Addr), node1.Connect(node2.Addr)}
+	for _, res := range results {
+		if err := <-res; err != nil {
+			t.Fatalf("Handshake failed: %v", err)
+		}
+	}
+	if s := node1.HandshakeState(node2.Addr); s != pkg.HandshakeEstablished {
+		t.Errorf("Initiator is in state %v", s)
+	}
+	var states []pkg.HandshakeState
+	for len(states) < 2 {
+		select {
+		case ev := <-events:
+			if ev.Addr != node1.Addr || !ev.Inbound {
+				t.Fatalf("Unexpected event %+v", ev)
+			}
+			states = append(states, ev.State)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Receiver reported states %v", states)
+		}
+	}
+	if states[0] != pkg.HandshakeAwaitingAck || states[1] != pkg.HandshakeEstablished {
+		t.Errorf("Receiver went through states %v", states)
+	}
+
+	// Dialing a node that is not there fails.
+	if err := <-node1.Connect(fmt.Sprintf("localhost:%v", test.GetFreePort())); err == nil {
+		t.Errorf("Handshake with a closed port succeeded")
+	}
+}
+
+func TestWaitForPeer(t *testing.T) {
+	node1 := insecureNode()
+	node2 := insecureNode()
+	test.Start(t, node1)
+	test.Start(t, node2)
+	defer node1.Kill()
+	defer node2.Kill()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := node2.WaitForPeer(ctx, node1.Addr); err == nil {
+		t.Fatalf("Waiting for a peer that never connects succeeded")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- node2.WaitForPeer(ctx, node1.Addr)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	node1.ConnectToPeer(node2.Addr)
+	if err := <-done; err != nil {
+		t.Fatalf("Receiver did not see the initiator become a peer: %v", err)
+	}
+}
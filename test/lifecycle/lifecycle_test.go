@@ -0,0 +1,140 @@
+package lifecycle
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/faults"
+	"finalbruh/pkg/transport"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func memNode(mem *transport.Memory, nw *faults.Network) *pkg.Node {
+	c := pkg.DefaultConfig(0)
+	c.Transport = mem
+	c.Faults = nw
+	return pkg.New(c)
+}
+
+func TestStartError(t *testing.T) {
+	port := test.GetFreePort()
+	a := pkg.New(pkg.DefaultConfig(port))
+	test.Start(t, a)
+	defer a.Kill()
+	b := pkg.New(pkg.DefaultConfig(port))
+	if err := b.Start(context.Background()); err == nil {
+		t.Fatalf("Second node started on a port in use")
+	}
+	// A node that never started shuts down without waiting.
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutting down an unstarted node failed: %v", err)
+	}
+}
+
+// TestShutdownDrainsSends shuts a node down right after it changed its
+// group, while the change is still on its way to the member.
+func TestShutdownDrainsSends(t *testing.T) {
+	mem := transport.NewMemory()
+	nw := faults.NewNetwork(1)
+	a, b := memNode(mem, nw), memNode(mem, nw)
+	test.Start(t, a, b)
+	defer b.Kill()
+	a.NewGroup()
+	a.ConnectToPeer(b.Addr)
+	test.ChkNdPrs(t, a, []*pkg.Node{b})
+
+	nw.SetLink(a.Addr, b.Addr, faults.Fault{Delay: 300 * time.Millisecond})
+	a.AddAMember(b.Addr)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if b.Group.Key() != a.Group.Key() {
+		t.Fatalf("Group change was dropped at shutdown")
+	}
+	select {
+	case <-a.Done():
+	default:
+		t.Fatalf("Done not closed after Shutdown")
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	mem := transport.NewMemory()
+	nw := faults.NewNetwork(1)
+	a, b := memNode(mem, nw), memNode(mem, nw)
+	test.Start(t, a, b)
+	defer b.Kill()
+	a.NewGroup()
+	a.ConnectToPeer(b.Addr)
+	test.ChkNdPrs(t, a, []*pkg.Node{b})
+
+	nw.SetLink(a.Addr, b.Addr, faults.Fault{Delay: time.Minute})
+	a.AddAMember(b.Addr)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := a.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected DeadlineExceeded, got %v", err)
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Fatalf("Shutdown took %v past its deadline", took)
+	}
+}
+
+func TestCancelStartContext(t *testing.T) {
+	node := memNode(transport.NewMemory(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := node.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case <-node.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Node kept running after its context was cancelled")
+	}
+	if node.Err() != nil {
+		t.Fatalf("Cancelled node reported %v", node.Err())
+	}
+}
+
+// run starts a small network, exercises it and shuts it down.
+func run(t *testing.T) {
+	mem := transport.NewMemory()
+	nodes := make([]*pkg.Node, 3)
+	for i := range nodes {
+		nodes[i] = memNode(mem, nil)
+		test.Start(t, nodes[i])
+	}
+	leader := nodes[0]
+	leader.NewGroup()
+	for _, n := range nodes[1:] {
+		leader.ConnectToPeer(n.Addr)
+		leader.AddAMember(n.Addr)
+	}
+	leader.MessageMyGroup("hello")
+	leader.BroadcastAddr()
+	leader.KickAMember(nodes[1].Addr)
+	for _, n := range nodes {
+		n.Kill()
+	}
+}
+
+func TestNoGoroutineLeaks(t *testing.T) {
+	// The first run starts the goroutines gRPC keeps for the process.
+	run(t)
+	time.Sleep(100 * time.Millisecond)
+	before := runtime.NumGoroutine()
+	for i := 0; i < 3; i++ {
+		run(t)
+	}
+	if !test.WaitFor(func() bool { return runtime.NumGoroutine() <= before }, 5*time.Second) {
+		buf := make([]byte, 1<<16)
+		buf = buf[:runtime.Stack(buf, true)]
+		t.Fatalf("%v goroutines left running, %v before:\n%s", runtime.NumGoroutine(), before, buf)
+	}
+}
@@ -0,0 +1,131 @@
+package maintenance
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/transport"
+	"finalbruh/test"
+	"testing"
+	"time"
+)
+
+func TestSeedsAndPeerDiscovery(t *testing.T) {
+	mem := transport.NewMemory()
+	newNode := func(seeds []string) *pkg.Node {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.Seeds = seeds
+		c.PeerLimit = 3
+		c.MaintenanceInterval = 20 * time.Millisecond
+		return pkg.New(c)
+	}
+	seed := newNode(nil)
+	test.Start(t, seed)
+	defer seed.Kill()
+
+	nodes := make([]*pkg.Node, 3)
+	for i := range nodes {
+		nodes[i] = newNode([]string{seed.Addr})
+		test.Start(t, nodes[i])
+		defer nodes[i].Kill()
+	}
+
+	// Every node reaches its peer limit: the seed plus the two others,
+	// learned from the seed's address table.
+	ok := test.WaitFor(func() bool {
+		for _, n := range nodes {
+			if len(n.PeerDb.List()) < 3 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second)
+	if !ok {
+		t.Fatalf("Nodes did not discover each other through the seed")
+	}
+	for i, n := range nodes {
+		others := []*pkg.Node{seed}
+		for j, o := range nodes {
+			if i != j {
+				others = append(others, o)
+			}
+		}
+		test.ChkNdPrs(t, n, others)
+	}
+}
+
+func TestKillStopsMaintenance(t *testing.T) {
+	mem := transport.NewMemory()
+	c := pkg.DefaultConfig(0)
+	c.Transport = mem
+	c.Seeds = []string{"localhost:999"}
+	c.MaintenanceInterval = 10 * time.Millisecond
+	node := pkg.New(c)
+	test.Start(t, node)
+
+	done := make(chan struct{})
+	go func() {
+		node.Kill()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Kill did not stop the maintenance loop")
+	}
+}
+
+func TestPruneStaleAddrs(t *testing.T) {
+	mem := transport.NewMemory()
+	newNode := func() *pkg.Node {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.AddrMaxAge = 100 * time.Millisecond
+		c.AddrPruneInterval = 20 * time.Millisecond
+		return pkg.New(c)
+	}
+	a, b := newNode(), newNode()
+	test.Start(t, a)
+	test.Start(t, b)
+	defer a.Kill()
+	defer b.Kill()
+	a.ConnectToPeer(b.Addr)
+	test.ChkNdPrs(t, a, []*pkg.Node{b})
+	if err := a.AddrDb.Add(address.New("10.1.1.1:8000", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := test.WaitFor(func() bool {
+		return a.AddrDb.Get("10.1.1.1:8000") == nil
+	}, 5*time.Second)
+	if !ok {
+		t.Fatalf("Stale address was not pruned")
+	}
+	if a.AddrDb.Get(b.Addr) == nil {
+		t.Fatalf("Pruned the address of a peer")
+	}
+}
+
+// TestZeroIntervals runs nodes whose periodic tasks are all disabled.
+func TestZeroIntervals(t *testing.T) {
+	mem := transport.NewMemory()
+	newNode := func() *pkg.Node {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.ProbeInterval = 0
+		c.MaintenanceInterval = 0
+		c.BroadcastInterval = 0
+		c.RepublishInterval = 0
+		c.AddrPruneInterval = 0
+		return pkg.New(c)
+	}
+	a, b := newNode(), newNode()
+	test.Start(t, a)
+	test.Start(t, b)
+	defer a.Kill()
+	defer b.Kill()
+	a.ConnectToPeer(b.Addr)
+	test.ChkNdPrs(t, a, []*pkg.Node{b})
+	time.Sleep(50 * time.Millisecond)
+	test.ChkNdPrs(t, a, []*pkg.Node{b})
+}
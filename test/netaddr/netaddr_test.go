@@ -0,0 +1,109 @@
+package netaddr
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/netaddr"
+	"finalbruh/test"
+	"net"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	valid := map[string]netaddr.HostPort{
+		"localhost:80":    {Host: "localhost", Port: 80},
+		"10.0.0.1:8333":   {Host: "10.0.0.1", Port: 8333},
+		"[::1]:9000":      {Host: "::1", Port: 9000},
+		"[fe80::1%lo]:1":  {Host: "fe80::1%lo", Port: 1},
+		":0":              {Host: "", Port: 0},
+		"[::]:65535":      {Host: "::", Port: 65535},
+		"node.example:42": {Host: "node.example", Port: 42},
+	}
+	for s, want := range valid {
+		got, err := netaddr.Parse(s)
+		if err != nil || got != want {
+			t.Errorf("Parse(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	invalid := []string{"localhost", "::1:9000", "host:port", "host:70000", "host:-1", "[zz::1]:1", ""}
+	for _, s := range invalid {
+		if _, err := netaddr.Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded; want error", s)
+		}
+	}
+	if got := (netaddr.HostPort{Host: "::1", Port: 7}).String(); got != "[::1]:7" {
+		t.Errorf("String() = %v; want [::1]:7", got)
+	}
+}
+
+func TestPortZero(t *testing.T) {
+	c := pkg.DefaultConfig(0)
+	c.Insecure = true
+	c.ListenAddr = "127.0.0.1:0"
+	node := pkg.New(c)
+	test.Start(t, node)
+	defer node.Kill()
+
+	hp, err := netaddr.Parse(node.Addr)
+	if err != nil || hp.Host != "127.0.0.1" || hp.Port == 0 {
+		t.Errorf("Expected bound port to be advertised, got %v", node.Addr)
+	}
+}
+
+func TestAdvertiseAddr(t *testing.T) {
+	port := test.GetFreePort()
+	c := pkg.DefaultConfig(port)
+	c.Insecure = true
+	c.AdvertiseAddr = "localhost:0"
+	node := pkg.New(c)
+	test.Start(t, node)
+	defer node.Kill()
+
+	want := netaddr.HostPort{Host: "localhost", Port: port}.String()
+	if node.Addr != want {
+		t.Errorf("Expected advertised address %v, got %v", want, node.Addr)
+	}
+}
+
+func TestIPv6(t *testing.T) {
+	lis, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback not available")
+	}
+	lis.Close()
+
+	newNode := func() *pkg.Node {
+		c := pkg.DefaultConfig(0)
+		c.Insecure = true
+		c.ListenAddr = "[::1]:0"
+		return pkg.New(c)
+	}
+	node1 := newNode()
+	node2 := newNode()
+	test.Start(t, node1)
+	test.Start(t, node2)
+	defer node1.Kill()
+	defer node2.Kill()
+
+	node1.ConnectToPeer(node2.Addr)
+	test.ChkNdPrs(t, node1, []*pkg.Node{node2})
+	test.ChkNdPrs(t, node2, []*pkg.Node{node1})
+}
+
+func TestGroup(t *testing.T) {
+	cases := map[string]string{
+		"10.1.2.3:1":           "10.1.0.0/16",
+		"[2001:db8:1::1]:1":    "2001:db8::/32",
+		"[fe80::1%eth0]:1":     "fe80::/32",
+		"Example.com:1":        "example.com",
+		"[::ffff:10.1.2.3]:80": "10.1.0.0/16",
+	}
+	for addr, want := range cases {
+		hp, err := netaddr.Parse(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hp.Group(); got != want {
+			t.Errorf("Group of %v is %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package persist
+
+import (
+	"encoding/json"
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/address/addressdb"
+	"finalbruh/pkg/peer"
+	"finalbruh/pkg/transport"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddressDbSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addresses.json")
+	// The seed is one under which the three addresses do not collide.
+	adb, err := addressdb.OpenAddrManSeeded(path, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []string{"1.1.1.1:1", "2.2.2.2:1", "3.3.3.3:1"} {
+		if err := adb.Add(address.New(a, 7)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	adb.Good("2.2.2.2:1")
+	if err := adb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// A temporary file left by a crash mid-save is ignored.
+	if err := ioutil.WriteFile(path+".tmp123", []byte("{garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key saved in the file wins over another seed.
+	adb, err = addressdb.OpenAddrManSeeded(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer adb.Close()
+	if len(adb.List()) != 3 {
+		t.Fatalf("Reopened database has %v addresses, want 3", len(adb.List()))
+	}
+	if a := adb.Get("1.1.1.1:1"); a == nil || a.LastSeen != 7 {
+		t.Fatalf("Address record not restored")
+	}
+	// The tried address is still preferred over the new ones.
+	first := 0
+	for i := 0; i < 200; i++ {
+		if adb.Select(1, nil)[0].Addr == "2.2.2.2:1" {
+			first++
+		}
+	}
+	if first < 70 {
+		t.Fatalf("Tried address picked first %v of 200 times", first)
+	}
+}
+
+func TestPeerDbSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	pdb, err := peer.NewDb(false, 10, "", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdb.Add(peer.New(address.New("1.1.1.1:1", 1), 0, &sk.PublicKey))
+	since := time.Now().Add(-time.Hour).Round(0)
+	in := peer.New(address.New("2.2.2.2:1", 1), 0, &sk.PublicKey)
+	in.Inbound, in.Since = true, since
+	pdb.Add(in)
+	pdb.Ban("6.6.6.6", time.Now().Add(time.Hour))
+	pdb.Ban("7.7.7.7", time.Now().Add(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	if err := pdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pdb, err = peer.NewDb(false, 10, "", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pdb.Close()
+	p := pdb.Get("1.1.1.1:1")
+	if p == nil || !p.PublicKey.Equal(&sk.PublicKey) || p.Inbound {
+		t.Fatalf("Peer not restored")
+	}
+	if p := pdb.Get("2.2.2.2:1"); p == nil || !p.Inbound || !p.Since.Equal(since) {
+		t.Fatalf("Inbound peer not restored as inbound peer since %v: %+v", since, p)
+	}
+	if !pdb.IsBanned("6.6.6.6") {
+		t.Fatalf("Ban not restored")
+	}
+	if pdb.IsBanned("7.7.7.7") {
+		t.Fatalf("Expired ban restored")
+	}
+}
+
+// TestPeerDbLoadsOldFile loads a peer file written before the inbound
+// flag was saved.
+func TestPeerDbLoadsOldFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	sk, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := utils.EncodePublicKey(&sk.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := json.Marshal(map[string]interface{}{
+		"Peers": []map[string]interface{}{
+			{"Addr": address.New("1.1.1.1:1", 1), "Version": 0, "PublicKey": pk},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, old, 0600); err != nil {
+		t.Fatal(err)
+	}
+	pdb, err := peer.NewDb(false, 10, "", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pdb.Close()
+	if p := pdb.Get("1.1.1.1:1"); p == nil || p.Inbound || p.Since.IsZero() {
+		t.Fatalf("Peer from old file not restored as outbound peer: %+v", p)
+	}
+}
+
+func TestNodeRestart(t *testing.T) {
+	mem := transport.NewMemory()
+	dir := t.TempDir()
+	newConf := func(dataDir string) *pkg.Config {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.DataDir = dataDir
+		return c
+	}
+	b := pkg.New(newConf(""))
+	test.Start(t, b)
+	defer b.Kill()
+
+	a := pkg.New(newConf(dir))
+	test.Start(t, a)
+	a.ConnectToPeer(b.Addr)
+	a.Ban("6.6.6.6", time.Hour)
+	test.ChkNdPrs(t, a, []*pkg.Node{b})
+	a.Kill()
+
+	// The restarted node has no seeds, so it only knows b from disk.
+	a2 := pkg.NewWithID(newConf(dir), a.Id)
+	test.Start(t, a2)
+	defer a2.Kill()
+	if a2.AddrDb.Get(b.Addr) == nil {
+		t.Fatalf("Restarted node forgot b's address")
+	}
+	if !a2.PeerDb.IsBanned("6.6.6.6") {
+		t.Fatalf("Restarted node forgot its bans")
+	}
+	ok := test.WaitFor(func() bool {
+		return a2.PeerDb.In(b.Addr) && b.PeerDb.In(a2.Addr)
+	}, 5*time.Second)
+	if !ok {
+		t.Fatalf("Restarted node did not reconnect to b")
+	}
+}
+
+// TestCorruptDataDir starts a node whose peer database cannot be read.
+func TestCorruptDataDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "peers.json")
+	if err := ioutil.WriteFile(path, []byte("{garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := pkg.DefaultConfig(0)
+	c.Transport = transport.NewMemory()
+	c.DataDir = dir
+	n := pkg.New(c)
+	if err := n.Start(context.Background()); err == nil {
+		n.Kill()
+		t.Fatalf("Started with an unreadable peer database")
+	}
+	n.Kill()
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "{garbage" {
+		t.Fatalf("Unreadable peer database was overwritten")
+	}
+}
+
+// TestFailedStartNotSaved starts a node on a port in use.
+func TestFailedStartNotSaved(t *testing.T) {
+	port := test.GetFreePort()
+	a := pkg.New(pkg.DefaultConfig(port))
+	test.Start(t, a)
+	defer a.Kill()
+
+	dir := t.TempDir()
+	c := pkg.DefaultConfig(port)
+	c.DataDir = dir
+	n := pkg.New(c)
+	if err := n.Start(context.Background()); err == nil {
+		n.Kill()
+		t.Fatalf("Second node started on a port in use")
+	}
+	n.Kill()
+	for _, name := range []string{"addresses.json", "peers.json"} {
+		if _, err := ioutil.ReadFile(filepath.Join(dir, name)); err == nil {
+			t.Errorf("Node that failed to start wrote %v", name)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package race
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/transport"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"fmt"
+	"golang.org/x/net/context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func memNode(mem *transport.Memory) *pkg.Node {
+	c := pkg.DefaultConfig(0)
+	c.Transport = mem
+	// Messages under a superseded key are expected here and must not
+	// get members banned or throttled. They barely count towards a
+	// ban, and are forgiven quickly.
+	c.ScoreDecay = time.Millisecond
+	c.PeerRate = 0
+	c.HostRate = 0
+	c.GlobalRate = 0
+	return pkg.New(c)
+}
+
+// TestConcurrentGroupAndGossip changes a group's membership while its
+// members gossip addresses and message the group. Run it with -race.
+func TestConcurrentGroupAndGossip(t *testing.T) {
+	mem := transport.NewMemory()
+	nodes := make([]*pkg.Node, 4)
+	for i := range nodes {
+		nodes[i] = memNode(mem)
+		test.Start(t, nodes[i])
+		defer nodes[i].Kill()
+	}
+	leader, members := nodes[0], nodes[1:]
+	leader.NewGroup()
+	for _, n := range members {
+		leader.ConnectToPeer(n.Addr)
+	}
+	test.ChkNdPrs(t, leader, members)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				f(i)
+			}
+		}()
+	}
+
+	for _, n := range members {
+		run(func(n *pkg.Node) func(int) {
+			return func(i int) {
+				if i%2 == 0 {
+					leader.AddAMember(n.Addr)
+				} else {
+					leader.KickAMember(n.Addr)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		}(n))
+	}
+	run(func(int) {
+		leader.MessageMyGroup("hello")
+		time.Sleep(time.Millisecond)
+	})
+	for j, n := range members {
+		sk, err := utils.GenerateAsymKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		run(func(j int, n *pkg.Node) func(int) {
+			return func(i int) {
+				ann, err := address.Announce(sk, fmt.Sprintf("10.%d.%d.1:8000", j, i%256))
+				if err != nil {
+					return
+				}
+				to := address.New(leader.Addr, 0)
+				_, _ = to.SendAddressesRPC(context.Background(), n.Conns, &proto.Addresses{Addrs: []*proto.Address{ann.Serialize()}})
+				_, _ = to.GroupMessageRPC(context.Background(), n.Conns, &proto.GroupIM{Encryptedmsg: "bm90IGEgbWVzc2FnZQ=="})
+				n.MessageMyGroup("hi")
+				_ = n.PeerDb.List()
+				_ = n.AddrDb.List()
+			}
+		}(j, n))
+	}
+
+	time.Sleep(2 * time.Second)
+	close(stop)
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, p := range leader.Group.Members() {
+		if seen[p.ID()] {
+			t.Fatalf("Member %v is in the group twice", p.ID())
+		}
+		seen[p.ID()] = true
+		if !leader.PeerDb.In(p.Addr.Addr) {
+			t.Fatalf("Group member %v is not a peer", p.Addr.Addr)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/ratelimit"
+	"finalbruh/pkg/transport"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := ratelimit.New(10, 3, 0, 0)
+	for i := 0; i < 3; i++ {
+		if !l.Allow("a", 1) {
+			t.Fatalf("Request %v within burst was refused", i)
+		}
+	}
+	if l.Allow("a", 1) {
+		t.Errorf("Request beyond burst was allowed")
+	}
+	if !l.Allow("b", 1) {
+		t.Errorf("Another peer was limited by a's usage")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !l.Allow("a", 1) {
+		t.Errorf("Bucket did not refill")
+	}
+
+	g := ratelimit.New(0, 0, 10, 2)
+	if !g.Allow("a", 1) || !g.Allow("b", 1) || g.Allow("c", 1) {
+		t.Errorf("Global limit not enforced across peers")
+	}
+
+	h := ratelimit.New(10, 3, 0, 0)
+	h.SetHostLimit(10, 4)
+	for i := 0; i < 4; i++ {
+		if !h.AllowFrom(string(rune('a'+i)), "host", 1) {
+			t.Fatalf("Request %v within host burst was refused", i)
+		}
+	}
+	if h.AllowFrom("e", "host", 1) {
+		t.Errorf("New key on a limited host was allowed")
+	}
+	if !h.AllowFrom("e", "other", 1) {
+		t.Errorf("Another host was limited by host's usage")
+	}
+	if h.AllowFrom("a", "host", 1) || !h.AllowFrom("a", "other", 1) || !h.AllowFrom("a", "other", 1) {
+		t.Errorf("Refused request was charged to the peer's bucket")
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	mem := transport.NewMemory()
+	c := pkg.DefaultConfig(0)
+	c.Insecure = true
+	c.Transport = mem
+	c.PeerBurst = 3
+	c.PeerRate = 0.1
+	c.MaxAddrsPerMsg = 2
+	node := pkg.New(c)
+	test.Start(t, node)
+	defer node.Kill()
+
+	cm := address.NewConnManager(10, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	a := address.New(node.Addr, 0)
+
+	addrs := &proto.Addresses{Addrs: []*proto.Address{{Addr: "a:1"}, {Addr: "b:1"}, {Addr: "c:1"}}}
+	if _, err := a.SendAddressesRPC(context.Background(), cm, addrs); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected oversized address list to be refused, got %v", err)
+	}
+
+	var err error
+	for i := 0; i < 5 && err == nil; i++ {
+		_, err = a.GetAddressesRPC(context.Background(), cm, &proto.GetAddressesRequest{})
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted once over the limit, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package retry
+
+import (
+	"bytes"
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/transport"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"sync"
+	"testing"
+)
+
+// flaky fails the first fails GroupMessage calls with code and records
+// the idempotency key of every call.
+type flaky struct {
+	*proto.UnimplementedBrunoCoinServer
+	fails int
+	code  codes.Code
+	keys  []string
+	sync.Mutex
+}
+
+func (f *flaky) GroupMessage(ctx context.Context, in *proto.GroupIM) (*proto.Empty, error) {
+	f.Lock()
+	defer f.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.keys = append(f.keys, md.Get(address.IdempotencyKey)...)
+	if len(f.keys) <= f.fails {
+		return nil, status.Error(f.code, "flaky")
+	}
+	return &proto.Empty{}, nil
+}
+
+func serve(t *testing.T, mem *transport.Memory, f *flaky) string {
+	lis, err := mem.Listen("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	proto.RegisterBrunoCoinServer(s, f)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestRetryPolicy(t *testing.T) {
+	mem := transport.NewMemory()
+	cm := address.NewConnManager(10, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	msg := &proto.GroupIM{Encryptedmsg: "x"}
+
+	f := &flaky{fails: 2, code: codes.Unavailable}
+	if _, err := address.New(serve(t, mem, f), 0).GroupMessageRPC(context.Background(), cm, msg); err != nil {
+		t.Fatalf("Transient failures were not retried: %v", err)
+	}
+	if len(f.keys) != 3 || f.keys[0] == "" || f.keys[0] != f.keys[1] || f.keys[1] != f.keys[2] {
+		t.Fatalf("Attempts carried idempotency keys %q, want three equal ones", f.keys)
+	}
+
+	f = &flaky{fails: 1, code: codes.InvalidArgument}
+	if _, err := address.New(serve(t, mem, f), 0).GroupMessageRPC(context.Background(), cm, msg); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v", err)
+	}
+	if len(f.keys) != 1 {
+		t.Fatalf("Permanent failure was attempted %v times", len(f.keys))
+	}
+
+	f = &flaky{fails: 10, code: codes.Unavailable}
+	if _, err := address.New(serve(t, mem, f), 0).GroupMessageRPC(context.Background(), cm, msg); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected Unavailable, got %v", err)
+	}
+	if want := address.DefaultRetryPolicies()["/BrunoCoin/GroupMessage"].MaxAttempts; len(f.keys) != want {
+		t.Fatalf("Failing RPC was attempted %v times, want %v", len(f.keys), want)
+	}
+
+	cm.SetRetryPolicies(nil)
+	f = &flaky{fails: 1, code: codes.Unavailable}
+	if _, err := address.New(serve(t, mem, f), 0).GroupMessageRPC(context.Background(), cm, msg); err == nil || len(f.keys) != 0 {
+		t.Fatalf("Retried with retries disabled")
+	}
+}
+
+// TestRepeatedKeyAppliedOnce registers with a CA twice under one key.
+// Every certificate the CA issues is new, so getting the same one back
+// shows the second call was not applied.
+func TestRepeatedKeyAppliedOnce(t *testing.T) {
+	mem := transport.NewMemory()
+	c := pkg.DefaultConfig(0)
+	c.Insecure = true
+	c.Transport = mem
+	ca := pkg.New(c)
+	test.Start(t, ca)
+	defer ca.Kill()
+
+	cm := address.NewConnManager(10, 0, nil, grpc.WithContextDialer(mem.Dial))
+	defer cm.Close()
+	client, err := cm.Get(ca.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := utils.EncodePublicKey(&sk.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	register := func(key string) []byte {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), address.IdempotencyKey, key)
+		cert, err := client.Register(ctx, &proto.Registration{Register: pk})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert.X509
+	}
+	first := register("k1")
+	if !bytes.Equal(register("k1"), first) {
+		t.Errorf("Repeated key was applied again")
+	}
+	if bytes.Equal(register("k2"), first) {
+		t.Errorf("Different keys got the same reply")
+	}
+
+	other, err := utils.GenerateAsymKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opk, err := utils.EncodePublicKey(&other.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), address.IdempotencyKey, "k1")
+	if _, err := client.Register(ctx, &proto.Registration{Register: opk}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected repeated key with another request to be refused, got %v", err)
+	}
+}
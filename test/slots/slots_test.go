@@ -0,0 +1,133 @@
+package slots
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/peer"
+	"finalbruh/pkg/transport"
+	"finalbruh/test"
+	"testing"
+	"time"
+)
+
+func newPeer(addr string, inbound bool) *peer.Peer {
+	p := peer.New(address.New(addr, 1), 0, nil)
+	p.Inbound = inbound
+	return p
+}
+
+func TestInboundEviction(t *testing.T) {
+	pdb, _ := peer.NewDb(true, 0, "", "")
+	pdb.SetSlots(2, 2)
+	for _, a := range []string{"1.1.1.1:1", "1.1.2.2:1"} {
+		if _, ok := pdb.Add(newPeer(a, true)); !ok {
+			t.Fatalf("Refused inbound peer %v with free slots", a)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// A peer from another network group replaces the newest peer of
+	// the crowded one.
+	evicted, ok := pdb.Add(newPeer("2.2.2.2:1", true))
+	if !ok || evicted == nil || evicted.Addr.Addr != "1.1.2.2:1" {
+		t.Fatalf("Diverse inbound peer did not replace the newest crowded one")
+	}
+	// With one peer per group, another newcomer is no better.
+	if _, ok := pdb.Add(newPeer("3.3.3.3:1", true)); ok {
+		t.Fatalf("Accepted an inbound peer that is no better")
+	}
+	// Unless an inbound peer misbehaved.
+	pdb.Misbehaving("1.1.1.1", 10)
+	evicted, ok = pdb.Add(newPeer("3.3.3.3:1", true))
+	if !ok || evicted == nil || evicted.Addr.Addr != "1.1.1.1:1" {
+		t.Fatalf("Misbehaving inbound peer was not replaced")
+	}
+}
+
+func TestOutboundProtected(t *testing.T) {
+	pdb, _ := peer.NewDb(true, 0, "", "")
+	pdb.SetSlots(2, 2)
+	pdb.Add(newPeer("1.1.1.1:1", false))
+	pdb.Add(newPeer("2.2.2.2:1", false))
+	if _, ok := pdb.Add(newPeer("3.3.3.3:1", false)); ok {
+		t.Fatalf("Outbound peer accepted past the limit")
+	}
+	// Inbound peers cannot take outbound slots, nor the reverse.
+	for _, a := range []string{"4.4.4.4:1", "5.5.5.5:1"} {
+		if _, ok := pdb.Add(newPeer(a, true)); !ok {
+			t.Fatalf("Inbound peer refused while only outbound slots are full")
+		}
+	}
+	if len(pdb.List()) != 4 {
+		t.Fatalf("Database holds %v peers, want 4", len(pdb.List()))
+	}
+	// A misbehaving outbound peer loses its slot to a new one; the
+	// good one keeps its slot.
+	pdb.Misbehaving("2.2.2.2", 10)
+	evicted, ok := pdb.Add(newPeer("3.3.3.3:1", false))
+	if !ok || evicted == nil || evicted.Addr.Addr != "2.2.2.2:1" {
+		t.Fatalf("Misbehaving outbound peer was not replaced")
+	}
+	if !pdb.In("1.1.1.1:1") {
+		t.Fatalf("Good outbound peer was evicted")
+	}
+}
+
+func TestDirectionKeptOnUpdate(t *testing.T) {
+	pdb, _ := peer.NewDb(true, 0, "", "")
+	pdb.SetSlots(2, 1)
+	pdb.Add(newPeer("1.1.1.1:1", false))
+	pdb.Add(newPeer("2.2.2.2:1", true))
+
+	// Dialing an inbound peer cannot push it past the outbound limit.
+	p := newPeer("2.2.2.2:1", false)
+	p.Addr.LastSeen = 2
+	if _, ok := pdb.Add(p); !ok {
+		t.Fatalf("Update of a known peer was refused")
+	}
+	if !pdb.Get("2.2.2.2:1").Inbound {
+		t.Fatalf("Inbound peer took an outbound slot past the limit")
+	}
+	// An outbound peer keeps its slot when it connects back.
+	p = newPeer("1.1.1.1:1", true)
+	p.Addr.LastSeen = 2
+	pdb.Add(p)
+	if pdb.Get("1.1.1.1:1").Inbound {
+		t.Fatalf("Outbound peer lost its slot when it connected back")
+	}
+
+	pdb.Remove("1.1.1.1:1")
+	p = newPeer("2.2.2.2:1", false)
+	p.Addr.LastSeen = 3
+	pdb.Add(p)
+	if pdb.Get("2.2.2.2:1").Inbound {
+		t.Fatalf("Dialed inbound peer did not take a free outbound slot")
+	}
+}
+
+func TestNodeInboundLimit(t *testing.T) {
+	mem := transport.NewMemory()
+	newNode := func() *pkg.Node {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.InboundLimit = 1
+		n := pkg.New(c)
+		test.Start(t, n)
+		return n
+	}
+	node, a, b := newNode(), newNode(), newNode()
+	defer node.Kill()
+	defer a.Kill()
+	defer b.Kill()
+
+	a.ConnectToPeer(node.Addr)
+	test.ChkNdPrs(t, a, []*pkg.Node{node})
+	b.ConnectToPeer(node.Addr)
+	if b.PeerDb.In(node.Addr) || node.PeerDb.In(b.Addr) {
+		t.Fatalf("Node accepted an inbound peer past its limit")
+	}
+	// The node can still dial out.
+	node.ConnectToPeer(b.Addr)
+	if !node.PeerDb.In(b.Addr) {
+		t.Fatalf("Full inbound slots kept the node from dialing out")
+	}
+}
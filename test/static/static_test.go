@@ -0,0 +1,123 @@
+package static
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/test"
+	"testing"
+	"time"
+)
+
+func insecureConfig(port int, static ...string) *pkg.Config {
+	c := pkg.DefaultConfig(port)
+	c.Insecure = true
+	c.StaticPeers = static
+	c.StaticRetryMin = 50 * time.Millisecond
+	c.StaticRetryMax = 200 * time.Millisecond
+	c.StaticRefreshInterval = 300 * time.Millisecond
+	return c
+}
+
+func insecureNode(port int, static ...string) *pkg.Node {
+	return pkg.New(insecureConfig(port, static...))
+}
+
+func TestStaticPeerReconnects(t *testing.T) {
+	port := test.GetFreePort()
+	b := insecureNode(port)
+	test.Start(t, b)
+	addr := b.Addr
+	b.Kill()
+
+	a := insecureNode(test.GetFreePort(), addr)
+	test.Start(t, a)
+	defer a.Kill()
+	ok := test.WaitFor(func() bool {
+		s := a.StaticPeers()
+		return len(s) == 1 && s[0].Failures >= 2
+	}, 5*time.Second)
+	if !ok {
+		t.Fatalf("Unreachable static peer was not retried: %+v", a.StaticPeers())
+	}
+	if s := a.StaticPeers()[0]; s.Connected || s.LastError == "" {
+		t.Fatalf("Unreachable static peer reported as %+v", s)
+	}
+
+	// The static peer comes up, and later restarts with an empty peer
+	// list. Both times the node connects to it without being asked.
+	for i := 0; i < 2; i++ {
+		b := insecureNode(port)
+		test.Start(t, b)
+		// The status is recorded once the handshake has returned, just
+		// after both sides added each other.
+		ok := test.WaitFor(func() bool {
+			return a.PeerDb.In(b.Addr) && b.PeerDb.In(a.Addr) && a.StaticPeers()[0].Connected
+		}, 5*time.Second)
+		if !ok {
+			b.Kill()
+			t.Fatalf("Node did not connect to its static peer (restart %v)", i)
+		}
+		if s := a.StaticPeers()[0]; !s.Connected || s.Failures != 0 {
+			b.Kill()
+			t.Fatalf("Connected static peer reported as %+v", s)
+		}
+		b.Kill()
+	}
+}
+
+func TestStaticPeerAfterResume(t *testing.T) {
+	b := insecureNode(test.GetFreePort())
+	test.Start(t, b)
+	defer b.Kill()
+	a := insecureNode(test.GetFreePort(), b.Addr)
+	test.Start(t, a)
+	defer a.Kill()
+	if !test.WaitFor(func() bool { return b.PeerDb.In(a.Addr) }, 5*time.Second) {
+		t.Fatalf("Node did not connect to its static peer")
+	}
+
+	a.PauseNetwork()
+	b.PeerDb.Remove(a.Addr)
+	a.ResumeNetwork()
+	if !test.WaitFor(func() bool { return b.PeerDb.In(a.Addr) }, 5*time.Second) {
+		t.Fatalf("Static peer did not learn of the node again after it resumed")
+	}
+}
+
+func TestStaticPeerWithFullSlots(t *testing.T) {
+	port := test.GetFreePort()
+	b := insecureNode(port)
+	test.Start(t, b)
+	addr := b.Addr
+	b.Kill()
+
+	c := insecureConfig(test.GetFreePort(), addr)
+	c.PeerLimit = 1
+	a := pkg.New(c)
+	test.Start(t, a)
+	defer a.Kill()
+	other := insecureNode(test.GetFreePort())
+	test.Start(t, other)
+	defer other.Kill()
+	a.ConnectToPeer(other.Addr)
+	if !a.PeerDb.In(other.Addr) {
+		t.Fatalf("Node did not connect to its first peer")
+	}
+
+	// The static peer comes up, and later restarts, while the node's
+	// only outbound slot is taken.
+	for i := 0; i < 2; i++ {
+		b := insecureNode(port)
+		test.Start(t, b)
+		ok := test.WaitFor(func() bool {
+			return a.PeerDb.In(b.Addr) && a.StaticPeers()[0].Connected
+		}, 5*time.Second)
+		b.Kill()
+		if !ok {
+			t.Fatalf("Node did not connect to its static peer (restart %v): %+v", i, a.StaticPeers())
+		}
+		if !a.PeerDb.In(other.Addr) {
+			t.Fatalf("Static peer took the place of another")
+		}
+		a.PeerDb.Remove(b.Addr)
+	}
+}
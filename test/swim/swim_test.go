@@ -0,0 +1,135 @@
+package swim
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/faults"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/swim"
+	"finalbruh/pkg/transport"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"testing"
+	"time"
+)
+
+func fastNodes(t *testing.T, n int, nw *faults.Network) []*pkg.Node {
+	mem := transport.NewMemory()
+	nodes := make([]*pkg.Node, n)
+	for i := range nodes {
+		c := pkg.DefaultConfig(0)
+		c.Transport = mem
+		c.Faults = nw
+		c.ProbeInterval = 20 * time.Millisecond
+		c.ProbeTimeout = 50 * time.Millisecond
+		c.SuspicionTimeout = 200 * time.Millisecond
+		nodes[i] = pkg.New(c)
+		test.Start(t, nodes[i])
+	}
+	for i := range nodes {
+		for j := i + 1; j < len(nodes); j++ {
+			nodes[i].ConnectToPeer(nodes[j].Addr)
+		}
+	}
+	return nodes
+}
+
+func TestDeadPeerEvicted(t *testing.T) {
+	nodes := fastNodes(t, 2, nil)
+	node1, node2 := nodes[0], nodes[1]
+	defer node1.Kill()
+	if !node1.PeerDb.In(node2.Addr) {
+		t.Fatalf("Nodes did not connect")
+	}
+	events := node1.Liveness()
+	node2.Kill()
+
+	var seen []swim.State
+	timeout := time.After(3 * time.Second)
+	for len(seen) < 2 {
+		select {
+		case ev := <-events:
+			if ev.Addr == node2.Addr {
+				seen = append(seen, ev.State)
+			}
+		case <-timeout:
+			t.Fatalf("Expected suspect and dead events, got %v", seen)
+		}
+	}
+	if seen[0] != swim.Suspect || seen[1] != swim.Dead {
+		t.Errorf("Expected suspect then dead, got %v", seen)
+	}
+	if !test.WaitFor(func() bool { return !node1.PeerDb.In(node2.Addr) }, time.Second) {
+		t.Errorf("Dead peer was not evicted")
+	}
+}
+
+func TestIndirectProbe(t *testing.T) {
+	nw := faults.NewNetwork(1)
+	nodes := fastNodes(t, 3, nw)
+	for _, n := range nodes {
+		defer n.Kill()
+	}
+	// node1 and node2 cannot reach each other, but both reach node3.
+	nw.Name("a", nodes[0].Addr)
+	nw.Name("b", nodes[1].Addr)
+	nw.Partition("a", "b")
+
+	time.Sleep(time.Second)
+	if !nodes[0].PeerDb.In(nodes[1].Addr) || !nodes[1].PeerDb.In(nodes[0].Addr) {
+		t.Errorf("Peer reachable through an indirect probe was evicted")
+	}
+}
+
+func TestProbesOnlyFromPeers(t *testing.T) {
+	nodes := fastNodes(t, 2, nil)
+	node, peer := nodes[0], nodes[1]
+	defer node.Kill()
+	defer peer.Kill()
+	c := pkg.DefaultConfig(0)
+	c.Transport = node.Conf.Transport
+	outsider := pkg.New(c)
+	test.Start(t, outsider)
+	defer outsider.Kill()
+	if !node.PeerDb.In(peer.Addr) {
+		t.Fatalf("Nodes did not connect")
+	}
+
+	a := address.New(node.Addr, 0)
+	ctx := context.Background()
+	if _, err := a.PingReqRPC(ctx, outsider.Conns, &proto.PingReqRequest{AddrMe: outsider.Addr, Target: peer.Addr}); err == nil {
+		t.Errorf("Node probed a target for a caller that is not a peer")
+	}
+	// Claiming a peer's address takes its key as well.
+	if _, err := a.PingRPC(ctx, outsider.Conns, &proto.PingRequest{AddrMe: peer.Addr}); err == nil {
+		t.Errorf("Node answered a ping from another key at a peer's address")
+	}
+	if _, err := a.PingReqRPC(ctx, outsider.Conns, &proto.PingReqRequest{AddrMe: peer.Addr, Target: peer.Addr}); err == nil {
+		t.Errorf("Node probed a target for another key at a peer's address")
+	}
+	if _, err := a.PingReqRPC(ctx, peer.Conns, &proto.PingReqRequest{AddrMe: peer.Addr, Target: peer.Addr}); err != nil {
+		t.Errorf("Node refused to probe a target for its peer: %v", err)
+	}
+
+	// Nor does it dial targets that are not its peers.
+	lis, err := c.Transport.Listen("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	dialed := make(chan struct{}, 1)
+	go func() {
+		if conn, err := lis.Accept(); err == nil {
+			dialed <- struct{}{}
+			conn.Close()
+		}
+	}()
+	if _, err := a.PingReqRPC(ctx, peer.Conns, &proto.PingReqRequest{AddrMe: peer.Addr, Target: lis.Addr().String()}); err == nil {
+		t.Errorf("Node probed a target that is not a peer")
+	}
+	select {
+	case <-dialed:
+		t.Errorf("Node dialed a target that is not a peer")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -16,11 +16,11 @@ func TestBasicSystem(t *testing.T) {
 	node3 := pkg.New(pkg.DefaultConfig(GetFreePort()))
 	node4 := pkg.New(pkg.DefaultConfig(GetFreePort()))
 
-	CAnode.Start()
-	node1.Start()
-	node2.Start()
-	node3.Start()
-	node4.Start()
+	Start(t, CAnode)
+	Start(t, node1)
+	Start(t, node2)
+	Start(t, node3)
+	Start(t, node4)
 
 	node1.ConnectToPeer(CAnode.Addr)
 	node2.ConnectToPeer(CAnode.Addr)
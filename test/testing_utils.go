@@ -3,8 +3,10 @@ package test
 import (
 	"finalbruh/pkg"
 	"github.com/phayes/freeport"
+	"golang.org/x/net/context"
 	"log"
 	"testing"
+	"time"
 )
 
 func GetFreePort() int {
@@ -22,3 +24,26 @@ func ChkNdPrs(t *testing.T, n *pkg.Node, prs []*pkg.Node) {
 		}
 	}
 }
+
+// WaitFor polls cond until it holds or timeout passes and reports
+// whether it held.
+func WaitFor(cond func() bool, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+// Start starts nodes, failing the test if any cannot start.
+func Start(t *testing.T, nodes ...*pkg.Node) {
+	t.Helper()
+	for _, n := range nodes {
+		if err := n.Start(context.Background()); err != nil {
+			t.Fatalf("Node failed to start: %v", err)
+		}
+	}
+}
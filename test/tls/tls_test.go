@@ -0,0 +1,124 @@
+package tls
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/address"
+	"finalbruh/pkg/id"
+	"finalbruh/pkg/proto"
+	"finalbruh/pkg/utils"
+	"finalbruh/test"
+	"golang.org/x/net/context"
+	"testing"
+	"time"
+)
+
+func TestMutualTLS(t *testing.T) {
+	node1 := pkg.New(pkg.DefaultConfig(test.GetFreePort()))
+	node2 := pkg.New(pkg.DefaultConfig(test.GetFreePort()))
+	test.Start(t, node1)
+	test.Start(t, node2)
+	defer node1.Kill()
+	defer node2.Kill()
+
+	node1.ConnectToPeer(node2.Addr)
+	time.Sleep(500 * time.Millisecond)
+
+	test.ChkNdPrs(t, node1, []*pkg.Node{node2})
+	test.ChkNdPrs(t, node2, []*pkg.Node{node1})
+}
+
+func TestInsecureClientRejected(t *testing.T) {
+	node := pkg.New(pkg.DefaultConfig(test.GetFreePort()))
+	test.Start(t, node)
+	defer node.Kill()
+
+	cm := address.NewConnManager(10, 0, nil)
+	defer cm.Close()
+	a := address.New(node.Addr, 0)
+	if _, err := a.GetAddressesRPC(context.Background(), cm, &proto.GetAddressesRequest{}); err == nil {
+		t.Errorf("Expected plaintext RPC to a TLS node to fail")
+	}
+}
+
+func TestTrustedCA(t *testing.T) {
+	CAnode := pkg.New(pkg.DefaultConfig(test.GetFreePort()))
+	caKey, err := utils.EncodePublicKey(&CAnode.Id.PrivateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Couldn't encode CA key")
+	}
+	newNode := func() *pkg.Node {
+		c := pkg.DefaultConfig(test.GetFreePort())
+		c.TrustedCA = caKey
+		return pkg.New(c)
+	}
+	node1 := newNode()
+	node2 := newNode()
+	node3 := newNode()
+	for _, n := range []*pkg.Node{CAnode, node1, node2, node3} {
+		test.Start(t, n)
+		defer n.Kill()
+	}
+
+	node1.ConnectToPeer(CAnode.Addr)
+	node2.ConnectToPeer(CAnode.Addr)
+	time.Sleep(500 * time.Millisecond)
+	node1.RegisterWithCA(CAnode.Addr)
+	node2.RegisterWithCA(CAnode.Addr)
+	time.Sleep(500 * time.Millisecond)
+
+	// Both nodes now present CA-issued certificates.
+	node1.ConnectToPeer(node2.Addr)
+	// node3 never registered, so its self-signed certificate is refused.
+	node1.ConnectToPeer(node3.Addr)
+	time.Sleep(500 * time.Millisecond)
+
+	test.ChkNdPrs(t, node1, []*pkg.Node{CAnode, node2})
+	test.ChkNdPrs(t, node2, []*pkg.Node{node1})
+	if node1.PeerDb.In(node3.Addr) || node3.PeerDb.In(node1.Addr) {
+		t.Errorf("Node without a CA-issued certificate was peered")
+	}
+}
+
+func TestBadTrustedCA(t *testing.T) {
+	c := pkg.DefaultConfig(test.GetFreePort())
+	c.TrustedCA = "not a key"
+	n := pkg.New(c)
+	defer n.Kill()
+	if err := n.Start(context.Background()); err == nil {
+		t.Fatalf("Started with a trusted CA key that could not be decoded")
+	}
+}
+
+func TestOnlyCAsIssue(t *testing.T) {
+	issuer, _ := utils.GenerateAsymKey()
+	leaf, _ := utils.GenerateAsymKey()
+
+	// A node's own certificate cannot vouch for another key.
+	self, err := id.SelfSignedCert(issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := id.IssueCert(issuer, self, &leaf.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := id.VerifyChain([][]byte{der, self}, &issuer.PublicKey); err == nil {
+		t.Errorf("Chain issued under a node certificate was accepted")
+	}
+	if _, err := id.VerifyChain([][]byte{self}, nil); err != nil {
+		t.Errorf("Self-signed node certificate was refused: %v", err)
+	}
+
+	ca, err := id.CACert(issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = id.IssueCert(issuer, ca, &leaf.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := id.VerifyChain([][]byte{der, ca}, &issuer.PublicKey)
+	if err != nil || !pk.Equal(&leaf.PublicKey) {
+		t.Errorf("Chain issued by a CA was refused: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"finalbruh/pkg"
+	"finalbruh/pkg/transport"
+	"finalbruh/test"
+	"testing"
+	"time"
+)
+
+func memNode(mem *transport.Memory) *pkg.Node {
+	c := pkg.DefaultConfig(0)
+	c.Transport = mem
+	return pkg.New(c)
+}
+
+func TestMemoryNetwork(t *testing.T) {
+	mem := transport.NewMemory()
+	nodes := make([]*pkg.Node, 5)
+	for i := range nodes {
+		nodes[i] = memNode(mem)
+		test.Start(t, nodes[i])
+		defer nodes[i].Kill()
+	}
+	for _, n := range nodes[1:] {
+		n.ConnectToPeer(nodes[0].Addr)
+	}
+	test.ChkNdPrs(t, nodes[0], nodes[1:])
+	for _, n := range nodes[1:] {
+		test.ChkNdPrs(t, n, nodes[:1])
+	}
+
+}
+
+func TestMemoryPauseResume(t *testing.T) {
+	mem := transport.NewMemory()
+	node1 := memNode(mem)
+	node2 := memNode(mem)
+	test.Start(t, node1)
+	test.Start(t, node2)
+	defer node1.Kill()
+	defer node2.Kill()
+
+	node2.PauseNetwork()
+	node1.ConnectToPeer(node2.Addr)
+	if node1.PeerDb.In(node2.Addr) {
+		t.Fatalf("Connected to a paused node")
+	}
+	node2.ResumeNetwork()
+	node1.ConnectToPeer(node2.Addr)
+	test.ChkNdPrs(t, node1, []*pkg.Node{node2})
+}
+
+func TestMemoryGroup(t *testing.T) {
+	mem := transport.NewMemory()
+	nodes := make([]*pkg.Node, 3)
+	for i := range nodes {
+		nodes[i] = memNode(mem)
+		test.Start(t, nodes[i])
+		defer nodes[i].Kill()
+	}
+	nodes[0].NewGroup()
+	for _, n := range nodes[1:] {
+		nodes[0].ConnectToPeer(n.Addr)
+		nodes[0].AddAMember(n.Addr)
+	}
+	ok := test.WaitFor(func() bool {
+		for _, n := range nodes[1:] {
+			if n.Group.Key() != nodes[0].Group.Key() || len(n.Group.Members()) != 2 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second)
+	if !ok {
+		t.Errorf("Group state did not converge")
+	}
+}